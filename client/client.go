@@ -0,0 +1,301 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package client implements a minimal TURN client (RFC 5766) sufficient to
+// authenticate, allocate a relay, and exchange data with peers through it
+// against this repository's Server.
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/stun/v2"
+	"github.com/pion/turn/v3/internal/proto"
+	"github.com/pion/turn/v3/internal/server"
+)
+
+// requestTimeout bounds how long Allocate/CreatePermission/Refresh wait for
+// a response before giving up.
+const requestTimeout = 5 * time.Second
+
+var (
+	errClientClosed       = errors.New("client is closed")
+	errNotListening       = errors.New("Listen must be called before Allocate")
+	errUnexpectedResponse = errors.New("unexpected STUN response")
+)
+
+// ClientConfig configures a Client.
+type ClientConfig struct {
+	// STUNServerAddr and TURNServerAddr are almost always the same address;
+	// they are kept distinct to mirror the server-side split between plain
+	// STUN binding discovery and TURN allocation, even though this client
+	// only ever speaks TURN to TURNServerAddr.
+	STUNServerAddr string
+	TURNServerAddr string
+
+	// Conn is the client's local socket; the caller owns opening it and
+	// Client.Close does not close it.
+	Conn net.PacketConn
+
+	Username string
+	Password string
+	Realm    string
+}
+
+// Client is a TURN client bound to one server and one local socket.
+// Construct one with NewClient.
+type Client struct {
+	conn           net.PacketConn
+	turnServerAddr net.Addr
+
+	username, password string
+	realm              string
+
+	pendingMu sync.Mutex
+	pending   map[string]chan *stun.Message
+
+	listening int32
+
+	relayMu sync.Mutex
+	relay   *relayConn
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewClient validates config and resolves TURNServerAddr. Call Listen
+// before Allocate.
+func NewClient(config *ClientConfig) (*Client, error) {
+	if config.Conn == nil {
+		return nil, errors.New("ClientConfig.Conn is required")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp4", config.TURNServerAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve TURNServerAddr: %w", err)
+	}
+
+	return &Client{
+		conn:           config.Conn,
+		turnServerAddr: addr,
+		username:       config.Username,
+		password:       config.Password,
+		realm:          config.Realm,
+		pending:        make(map[string]chan *stun.Message),
+		closed:         make(chan struct{}),
+	}, nil
+}
+
+// Listen starts the goroutine that reads every incoming STUN response,
+// Data indication, and ChannelData frame off Conn. It must be called
+// before Allocate.
+func (c *Client) Listen() error {
+	atomic.StoreInt32(&c.listening, 1)
+	go c.readLoop()
+	return nil
+}
+
+// Close tears down the client: it best-effort Refreshes the allocation to
+// Lifetime 0 and makes pending and later requests fail with
+// errClientClosed. It does not close Conn, which the caller owns, so the
+// read loop keeps running until a read from Conn fails.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.relayMu.Lock()
+		hasAllocation := c.relay != nil
+		c.relayMu.Unlock()
+
+		if hasAllocation {
+			_, _ = c.request(stun.NewType(stun.MethodRefresh, stun.ClassRequest), proto.Lifetime{Duration: 0})
+		}
+		close(c.closed)
+	})
+	return nil
+}
+
+// readLoop demultiplexes every packet read off c.conn: STUN responses are
+// delivered to the pending request that is waiting for their transaction
+// ID; Data indications and ChannelData frames are delivered to the relay
+// connection returned by Allocate, if any.
+func (c *Client) readLoop() {
+	buf := make([]byte, 1600)
+	for {
+		n, _, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		raw := append([]byte{}, buf[:n]...)
+
+		if stun.IsMessage(raw) {
+			c.handleSTUN(raw)
+			continue
+		}
+		if proto.IsChannelData(raw) {
+			c.handleChannelData(raw)
+		}
+	}
+}
+
+func (c *Client) handleSTUN(raw []byte) {
+	msg := &stun.Message{Raw: raw}
+	if err := msg.Decode(); err != nil {
+		return
+	}
+
+	if msg.Type.Method == stun.MethodData && msg.Type.Class == stun.ClassIndication {
+		var peer proto.PeerAddress
+		var data proto.Data
+		if peer.GetFrom(msg) != nil || data.GetFrom(msg) != nil {
+			return
+		}
+		c.relayMu.Lock()
+		rc := c.relay
+		c.relayMu.Unlock()
+		if rc != nil {
+			rc.deliver(&net.UDPAddr{IP: peer.IP, Port: peer.Port}, data)
+		}
+		return
+	}
+
+	txID := string(msg.TransactionID[:])
+	c.pendingMu.Lock()
+	ch, ok := c.pending[txID]
+	c.pendingMu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (c *Client) handleChannelData(raw []byte) {
+	channel, data, err := proto.ParseChannelData(raw)
+	if err != nil {
+		return
+	}
+
+	c.relayMu.Lock()
+	rc := c.relay
+	c.relayMu.Unlock()
+	if rc == nil {
+		return
+	}
+
+	peer, ok := rc.peerForChannel(channel)
+	if !ok {
+		return
+	}
+	rc.deliver(peer, data)
+}
+
+// request sends a STUN request built from attrs without credentials and, if
+// the server challenges it (401 or 438), retries once with the challenge's
+// nonce and realm plus the long-term credential attributes, returning the
+// response. It is used for every request type this client sends (Allocate,
+// Refresh, CreatePermission).
+func (c *Client) request(msgType stun.MessageType, attrs ...stun.Setter) (*stun.Message, error) {
+	resp, err := c.doRequest(msgType, attrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Type.Class != stun.ClassErrorResponse {
+		return resp, nil
+	}
+
+	var errCode stun.ErrorCodeAttribute
+	if err := errCode.GetFrom(resp); err != nil {
+		return nil, errUnexpectedResponse
+	}
+	if errCode.Code != stun.CodeUnauthorized && errCode.Code != stun.CodeStaleNonce {
+		return nil, fmt.Errorf("%w: %s", errUnexpectedResponse, errCode)
+	}
+
+	var nonce stun.Nonce
+	var realm stun.Realm
+	if nonce.GetFrom(resp) != nil || realm.GetFrom(resp) != nil {
+		return nil, errUnexpectedResponse
+	}
+	c.realm = realm.String()
+
+	authed := append(append([]stun.Setter{}, attrs...),
+		stun.NewUsername(c.username),
+		stun.NewRealm(c.realm),
+		nonce,
+		stun.NewLongTermIntegrity(c.username, c.realm, c.password),
+	)
+	return c.doRequest(msgType, authed...)
+}
+
+// doRequest sends one STUN request and waits up to requestTimeout for the
+// response matching its transaction ID.
+func (c *Client) doRequest(msgType stun.MessageType, attrs ...stun.Setter) (*stun.Message, error) {
+	select {
+	case <-c.closed:
+		return nil, errClientClosed
+	default:
+	}
+
+	txID := stun.NewTransactionID()
+	ch := make(chan *stun.Message, 1)
+
+	c.pendingMu.Lock()
+	c.pending[string(txID[:])] = ch
+	c.pendingMu.Unlock()
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pending, string(txID[:]))
+		c.pendingMu.Unlock()
+	}()
+
+	if err := server.SendMessage(c.conn, c.turnServerAddr, server.BuildMessage(txID, msgType, attrs...)...); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-time.After(requestTimeout):
+		return nil, fmt.Errorf("timed out waiting for %s response", msgType)
+	case <-c.closed:
+		return nil, errClientClosed
+	}
+}
+
+// Allocate sends an Allocate request, authenticating against the server's
+// challenge, and returns a net.PacketConn relaying through the allocation it
+// creates.
+func (c *Client) Allocate() (net.PacketConn, error) {
+	if atomic.LoadInt32(&c.listening) == 0 {
+		return nil, errNotListening
+	}
+
+	resp, err := c.request(
+		stun.NewType(stun.MethodAllocate, stun.ClassRequest),
+		proto.RequestedTransport{Protocol: proto.ProtoUDP},
+		proto.Lifetime{Duration: proto.DefaultLifetime},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Type.Class != stun.ClassSuccessResponse {
+		var errCode stun.ErrorCodeAttribute
+		_ = errCode.GetFrom(resp)
+		return nil, fmt.Errorf("Allocate failed: %s", errCode) //nolint:stylecheck
+	}
+
+	var relayedAddr proto.RelayedAddress
+	if err := relayedAddr.GetFrom(resp); err != nil {
+		return nil, fmt.Errorf("Allocate response missing XOR-RELAYED-ADDRESS: %w", err) //nolint:stylecheck
+	}
+
+	rc := newRelayConn(c, &net.UDPAddr{IP: relayedAddr.IP, Port: relayedAddr.Port})
+	c.relayMu.Lock()
+	c.relay = rc
+	c.relayMu.Unlock()
+
+	return rc, nil
+}
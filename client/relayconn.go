@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/stun/v2"
+	"github.com/pion/turn/v3/internal/proto"
+	"github.com/pion/turn/v3/internal/server"
+)
+
+// inboundFrame is one payload delivered by a Data indication or ChannelData
+// frame, queued for a relayConn.ReadFrom caller.
+type inboundFrame struct {
+	peer net.Addr
+	data []byte
+}
+
+// relayConn is the net.PacketConn Client.Allocate returns: writes go out as
+// Send indications through the TURN allocation (this client never binds a
+// channel), and reads come from Data indications and ChannelData frames the
+// client's readLoop hands it.
+type relayConn struct {
+	client     *Client
+	relayAddr  net.Addr
+	inbound    chan inboundFrame
+	closed     chan struct{}
+	closeOnce  sync.Once
+
+	channelsMu    sync.Mutex
+	channelToPeer map[proto.ChannelNumber]net.Addr
+
+	permissionsMu sync.Mutex
+	permissions   map[string]bool
+}
+
+func newRelayConn(c *Client, relayAddr net.Addr) *relayConn {
+	return &relayConn{
+		client:        c,
+		relayAddr:     relayAddr,
+		inbound:       make(chan inboundFrame, 16),
+		closed:        make(chan struct{}),
+		channelToPeer: make(map[proto.ChannelNumber]net.Addr),
+		permissions:   make(map[string]bool),
+	}
+}
+
+// deliver queues a frame received from peer for the next ReadFrom.
+func (r *relayConn) deliver(peer net.Addr, data []byte) {
+	select {
+	case r.inbound <- inboundFrame{peer: peer, data: append([]byte{}, data...)}:
+	case <-r.closed:
+	}
+}
+
+func (r *relayConn) peerForChannel(channel proto.ChannelNumber) (net.Addr, bool) {
+	r.channelsMu.Lock()
+	defer r.channelsMu.Unlock()
+	peer, ok := r.channelToPeer[channel]
+	return peer, ok
+}
+
+// ensurePermission installs a CreatePermission for peer the first time this
+// relayConn writes to it; RFC 5766 section 9.1 requires one before any Send
+// or ChannelData reaches that peer.
+func (r *relayConn) ensurePermission(peer *net.UDPAddr) error {
+	r.permissionsMu.Lock()
+	ok := r.permissions[peer.String()]
+	r.permissionsMu.Unlock()
+	if ok {
+		return nil
+	}
+
+	if _, err := r.client.request(
+		stun.NewType(stun.MethodCreatePermission, stun.ClassRequest),
+		&proto.PeerAddress{XORMappedAddress: stun.XORMappedAddress{IP: peer.IP, Port: peer.Port}},
+	); err != nil {
+		return err
+	}
+
+	r.permissionsMu.Lock()
+	r.permissions[peer.String()] = true
+	r.permissionsMu.Unlock()
+	return nil
+}
+
+// ReadFrom implements net.PacketConn.
+func (r *relayConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case frame := <-r.inbound:
+		return copy(p, frame.data), frame.peer, nil
+	case <-r.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+// WriteTo implements net.PacketConn: it installs a permission for addr if
+// needed, then sends p as a Send indication.
+func (r *relayConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	peer, ok := addr.(*net.UDPAddr)
+	if !ok {
+		resolved, err := net.ResolveUDPAddr("udp4", addr.String())
+		if err != nil {
+			return 0, err
+		}
+		peer = resolved
+	}
+
+	if err := r.ensurePermission(peer); err != nil {
+		return 0, err
+	}
+
+	if err := server.SendMessage(r.client.conn, r.client.turnServerAddr, server.BuildMessage(
+		stun.NewTransactionID(),
+		stun.NewType(stun.MethodSend, stun.ClassIndication),
+		&proto.PeerAddress{XORMappedAddress: stun.XORMappedAddress{IP: peer.IP, Port: peer.Port}},
+		proto.Data(p),
+	)...); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close implements net.PacketConn. After it, ReadFrom returns net.ErrClosed
+// (or a frame already queued) and deliver no longer blocks. The relay stays
+// attached to the client, and the allocation itself is not torn down;
+// Client.Close does that with a Refresh(0).
+func (r *relayConn) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+	return nil
+}
+
+// LocalAddr implements net.PacketConn, returning the allocation's
+// XOR-RELAYED-ADDRESS: the address peers must send to in order to reach
+// this client.
+func (r *relayConn) LocalAddr() net.Addr { return r.relayAddr }
+
+func (r *relayConn) SetDeadline(time.Time) error      { return nil }
+func (r *relayConn) SetReadDeadline(time.Time) error  { return nil }
+func (r *relayConn) SetWriteDeadline(time.Time) error { return nil }
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxDTLSPacketSize bounds a single read off an accepted DTLS session; TURN
+// traffic never exceeds the Ethernet MTU.
+const maxDTLSPacketSize = 1600
+
+// dtlsPacket is one payload read off an accepted DTLS session, tagged with
+// the peer it came from so dtlsPacketConn.ReadFrom can report it.
+type dtlsPacket struct {
+	addr net.Addr
+	data []byte
+}
+
+// dtlsPacketConn adapts a net.Listener of per-peer DTLS sessions (as
+// returned by dtls.Listen) into the single shared net.PacketConn that
+// turn.PacketConnConfig expects. DTLS is inherently connection-oriented, so
+// unlike a real UDP socket this can only write to a peer that has already
+// completed a handshake by being Accept()ed; reads are demultiplexed across
+// every accepted session.
+type dtlsPacketConn struct {
+	ln        net.Listener
+	localAddr net.Addr
+
+	connsMu sync.Mutex
+	conns   map[string]net.Conn
+
+	inbound   chan dtlsPacket
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newDTLSPacketConn(ln net.Listener) *dtlsPacketConn {
+	pc := &dtlsPacketConn{
+		ln:        ln,
+		localAddr: ln.Addr(),
+		conns:     make(map[string]net.Conn),
+		inbound:   make(chan dtlsPacket, 16),
+		closed:    make(chan struct{}),
+	}
+	go pc.acceptLoop()
+	return pc
+}
+
+func (pc *dtlsPacketConn) acceptLoop() {
+	for {
+		conn, err := pc.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		pc.connsMu.Lock()
+		pc.conns[conn.RemoteAddr().String()] = conn
+		pc.connsMu.Unlock()
+
+		go pc.readLoop(conn)
+	}
+}
+
+func (pc *dtlsPacketConn) readLoop(conn net.Conn) {
+	defer func() {
+		pc.connsMu.Lock()
+		delete(pc.conns, conn.RemoteAddr().String())
+		pc.connsMu.Unlock()
+	}()
+
+	buf := make([]byte, maxDTLSPacketSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+
+		select {
+		case pc.inbound <- dtlsPacket{addr: conn.RemoteAddr(), data: append([]byte{}, buf[:n]...)}:
+		case <-pc.closed:
+			return
+		}
+	}
+}
+
+// ReadFrom implements net.PacketConn.
+func (pc *dtlsPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case pkt := <-pc.inbound:
+		return copy(p, pkt.data), pkt.addr, nil
+	case <-pc.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+// WriteTo implements net.PacketConn. It only succeeds for a peer that
+// already has an accepted DTLS session, since the server side of a DTLS
+// handshake cannot be initiated by a write.
+func (pc *dtlsPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	pc.connsMu.Lock()
+	conn, ok := pc.conns[addr.String()]
+	pc.connsMu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("no DTLS session established with %s", addr)
+	}
+	return conn.Write(p)
+}
+
+// Close implements net.PacketConn.
+func (pc *dtlsPacketConn) Close() error {
+	var err error
+	pc.closeOnce.Do(func() {
+		close(pc.closed)
+		err = pc.ln.Close()
+	})
+	return err
+}
+
+// LocalAddr implements net.PacketConn.
+func (pc *dtlsPacketConn) LocalAddr() net.Addr { return pc.localAddr }
+
+func (pc *dtlsPacketConn) SetDeadline(time.Time) error      { return nil }
+func (pc *dtlsPacketConn) SetReadDeadline(time.Time) error  { return nil }
+func (pc *dtlsPacketConn) SetWriteDeadline(time.Time) error { return nil }
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"io"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"regexp"
@@ -22,16 +24,28 @@ import (
 )
 
 type Configuration struct {
-	PublicIp    string            `json:"public-ip"`
-	Port        int               `json:"port"`
-	UsersMap    map[string]string `json:"users-map"`
-	Realm       string            `json:"realm"`
-	LogConsole  bool              `json:"log-console"`
-	LogFile     bool              `json:"log-file"`
-	LogFilePath string            `json:"log-file-path"`
-	MultiThread bool              `json:"multi-thread"`
-	ThreadCount int               `json:"thread-count"`
-	SocketType  string            `json:"socket-type"` // (tcp),(udp),(tcp,udp)
+	PublicIp    string                 `json:"public-ip"`
+	Port        int                    `json:"port"`
+	UsersMap    map[string]string      `json:"users-map"`
+	AuthSecret  string                 `json:"auth-secret"`
+	Realm       string                 `json:"realm"`
+	LogConsole  bool                   `json:"log-console"`
+	LogFile     bool                   `json:"log-file"`
+	LogFilePath string                 `json:"log-file-path"`
+	MultiThread bool                   `json:"multi-thread"`
+	ThreadCount int                    `json:"thread-count"`
+	SocketType  string                 `json:"socket-type"` // (tcp),(udp),(tcp,udp)
+	TLSPort     int                    `json:"tls-port"`    // 0 disables turns: support
+	CertFile    string                 `json:"cert-file"`
+	KeyFile     string                 `json:"key-file"`
+	MetricsAddr string                 `json:"metrics-addr"` // e.g. "0.0.0.0:9090"; empty disables the Prometheus endpoint
+	UsersQuota  map[string]QuotaConfig `json:"users-quota"`   // per-username bandwidth quota, keyed like users-map
+}
+
+// QuotaConfig is the JSON shape of one entry in "users-quota".
+type QuotaConfig struct {
+	BytesPerSecond int   `json:"bytes-per-second"`
+	MonthlyByteCap int64 `json:"monthly-byte-cap"`
 }
 
 var configuration = &Configuration{
@@ -45,12 +59,22 @@ var configuration = &Configuration{
 	SocketType:  "tcp,udp",
 }
 
+// metricsCollector is non-nil once "metrics-addr" is configured and is
+// shared by all ServerConfigs so every worker in multi-thread mode reports
+// into the same counters.
+var metricsCollector *turn.PrometheusMetricsCollector
+
 func loadConfig() {
 	configPath := flag.String("config", "", "Json config file path.")
 	publicIP := flag.String("public-ip", "", "IP Address that TURN can be contacted by.")
 	port := flag.Int("port", -1, "Listening port.")
+	tlsPort := flag.Int("tls-port", -1, "Listening port for turns: (TLS/DTLS), 0 disables it.")
+	certFile := flag.String("cert-file", "", "PEM certificate file, required when tls-port is set.")
+	keyFile := flag.String("key-file", "", "PEM key file, required when tls-port is set.")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on (e.g. \"0.0.0.0:9090\"); empty disables it.")
 	realm := flag.String("realm", "kerry", "Realm (defaults to \"kerry\")")
 	users := flag.String("users", "", "List of username and password (e.g. \"user=pass,user=pass\")")
+	authSecret := flag.String("auth-secret", "", "Shared secret for time-limited TURN REST credentials (users from -users take priority when both are set)")
 	socketType := flag.String("socket-type", "", "support socket type (e.g. \"tcp; udp; tcp,udp\")")
 	flag.Parse()
 
@@ -96,14 +120,71 @@ func loadConfig() {
 	if len(*socketType) > 0 {
 		configuration.SocketType = *socketType
 	}
+	if len(*authSecret) > 0 {
+		configuration.AuthSecret = *authSecret
+	}
+	if *tlsPort >= 0 {
+		configuration.TLSPort = *tlsPort
+	}
+	if len(*certFile) > 0 {
+		configuration.CertFile = *certFile
+	}
+	if len(*keyFile) > 0 {
+		configuration.KeyFile = *keyFile
+	}
+	if len(*metricsAddr) > 0 {
+		configuration.MetricsAddr = *metricsAddr
+	}
 }
 
 func checkConfig() {
 	if len(configuration.PublicIp) == 0 {
 		log.Fatalf("'public-ip' is required")
 	}
-	if len(configuration.UsersMap) == 0 {
-		log.Fatalf("'users-map' is required")
+	if len(configuration.UsersMap) == 0 && len(configuration.AuthSecret) == 0 {
+		log.Fatalf("either 'users-map' or 'auth-secret' is required")
+	}
+	if configuration.TLSPort > 0 && (len(configuration.CertFile) == 0 || len(configuration.KeyFile) == 0) {
+		log.Fatalf("'cert-file' and 'key-file' are required when 'tls-port' is set")
+	}
+}
+
+// buildAuthHandler returns the AuthHandler for the configured authentication
+// mode. 'auth-secret' lets operators hand out short-lived REST-style TURN
+// credentials without provisioning per-user state; 'users-map' keeps the
+// static username/password list for simple deployments. If both are set,
+// users-map is tried first and auth-secret is used as a fallback.
+func buildAuthHandler(usersMap map[string][]byte) turn.AuthHandler {
+	var secretHandler turn.AuthHandler
+	if len(configuration.AuthSecret) > 0 {
+		secretHandler = turn.RESTAuthHandler(configuration.AuthSecret)
+	}
+
+	return func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+		if key, ok := usersMap[username]; ok {
+			return key, true
+		}
+		if secretHandler != nil {
+			return secretHandler(username, realm, srcAddr)
+		}
+		return nil, false
+	}
+}
+
+// buildQuotaHandler returns the turn.QuotaHandler backed by the "users-quota"
+// config map, so the data path can enforce a per-username rate limit and
+// monthly byte cap without any extra per-allocation provisioning. Usernames
+// absent from the map are unrestricted.
+func buildQuotaHandler() turn.QuotaHandler {
+	return func(username, realm string) turn.Quota {
+		cfg, ok := configuration.UsersQuota[username]
+		if !ok {
+			return turn.Quota{}
+		}
+		return turn.Quota{
+			BytesPerSecond: cfg.BytesPerSecond,
+			MonthlyByteCap: cfg.MonthlyByteCap,
+		}
 	}
 }
 
@@ -144,6 +225,16 @@ func main() {
 		usersMap[username] = turn.GenerateAuthKey(username, configuration.Realm, password)
 	}
 
+	if len(configuration.MetricsAddr) > 0 {
+		metricsCollector = turn.NewPrometheusMetricsCollector()
+		go func() {
+			log.Printf("serving Prometheus metrics on %v\n", configuration.MetricsAddr)
+			if err := http.ListenAndServe(configuration.MetricsAddr, metricsCollector.Handler()); err != nil { //nolint:gosec
+				log.Printf("metrics server stopped: %s\n", err)
+			}
+		}()
+	}
+
 	if configuration.MultiThread {
 		var threadNum int
 		if configuration.ThreadCount == 0 {
@@ -208,21 +299,32 @@ func singleThreadSocket(usersMap map[string][]byte) {
 		listenerConfigs = make([]turn.ListenerConfig, 0)
 	}
 
+	var reloader *certReloader
+	if configuration.TLSPort > 0 {
+		reloader = newCertReloader(configuration.CertFile, configuration.KeyFile)
+		tlsPacketConnConfigs, tlsListenerConfigs := tlsAndDTLSConfigs(reloader, relayAddressGenerator)
+		packetConnConfigs = append(packetConnConfigs, tlsPacketConnConfigs...)
+		listenerConfigs = append(listenerConfigs, tlsListenerConfigs...)
+	}
+
 	if len(packetConnConfigs) == 0 && len(listenerConfigs) == 0 {
 		log.Panic("socketType must be tcp or udp or both(tcp,udp)")
 	}
 
-	s, err := turn.NewServer(turn.ServerConfig{
-		Realm: configuration.Realm,
-		AuthHandler: func(username string, realm string, srcAddr net.Addr) ([]byte, bool) {
-			if key, ok := usersMap[username]; ok {
-				return key, true
-			}
-			return nil, false
-		},
+	serverConfig := turn.ServerConfig{
+		Realm:             configuration.Realm,
+		AuthHandler:       buildAuthHandler(usersMap),
 		PacketConnConfigs: packetConnConfigs,
 		ListenerConfigs:   listenerConfigs,
-	})
+	}
+	if metricsCollector != nil {
+		serverConfig.MetricsCollector = metricsCollector
+	}
+	if len(configuration.UsersQuota) > 0 {
+		serverConfig.QuotaHandler = buildQuotaHandler()
+	}
+
+	s, err := turn.NewServer(serverConfig)
 
 	if err != nil {
 		log.Panic(err)
@@ -230,10 +332,17 @@ func singleThreadSocket(usersMap map[string][]byte) {
 
 	log.Printf("create TURN server success\n")
 
-	// Block until user sends SIGINT or SIGTERM
+	// Block until user sends SIGINT or SIGTERM, reloading the turns:
+	// certificate from disk in place whenever SIGHUP arrives.
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	<-sigs
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigs {
+		if sig == syscall.SIGHUP && reloader != nil {
+			reloader.reload()
+			continue
+		}
+		break
+	}
 
 	if err = s.Close(); err != nil {
 		log.Panic(err)
@@ -244,5 +353,101 @@ func multiThreadSocket(usersMap map[string][]byte, threadNum int) {
 
 	log.Printf("start TURN server in multi thread mode. threadNum = %v. addr = %v:%v\n", threadNum, configuration.PublicIp, configuration.Port)
 
-	log.Panicf("no impp for multi thread mode")
+	if !reuseportAvailable {
+		log.Panicf("multi thread mode is not supported on this platform, fall back to single thread mode (multi-thread: false)")
+	}
+
+	relayAddressGenerator := &turn.RelayAddressGeneratorStatic{
+		RelayAddress: net.ParseIP(configuration.PublicIp), // Claim that we are listening on IP passed by user
+		Address:      "0.0.0.0",                           // But actually be listening on every interface
+	}
+
+	listenConfig := net.ListenConfig{Control: reuseportControl}
+	addr := "0.0.0.0:" + strconv.Itoa(configuration.Port)
+
+	// udp: every worker opens its own SO_REUSEPORT socket bound to the same
+	// address/port, and the kernel load-balances packets across them by
+	// 5-tuple hash, so a given client always lands on the same worker
+	// socket. All sockets feed one turn.Server, which holds every worker's
+	// allocations in a single allocation manager.
+	var packetConnConfigs []turn.PacketConnConfig
+	if strings.Contains(configuration.SocketType, "udp") {
+		for i := 0; i < threadNum; i++ {
+			conn, err := listenConfig.ListenPacket(context.Background(), "udp4", addr)
+			if err != nil {
+				log.Panicf("Failed to create UDP TURN server listener %d/%d: %s", i+1, threadNum, err)
+			}
+			packetConnConfigs = append(packetConnConfigs, turn.PacketConnConfig{
+				PacketConn:            conn,
+				RelayAddressGenerator: relayAddressGenerator,
+			})
+		}
+		log.Printf("created %d UDP TURN server listeners at port %d\n", threadNum, configuration.Port)
+	}
+
+	// tcp
+	var listenerConfigs []turn.ListenerConfig
+	if strings.Contains(configuration.SocketType, "tcp") {
+		for i := 0; i < threadNum; i++ {
+			ln, err := listenConfig.Listen(context.Background(), "tcp4", addr)
+			if err != nil {
+				log.Panicf("Failed to create TCP TURN server listener %d/%d: %s", i+1, threadNum, err)
+			}
+			listenerConfigs = append(listenerConfigs, turn.ListenerConfig{
+				Listener:              ln,
+				RelayAddressGenerator: relayAddressGenerator,
+			})
+		}
+		log.Printf("created %d TCP TURN server listeners at port %d\n", threadNum, configuration.Port)
+	}
+
+	var reloader *certReloader
+	if configuration.TLSPort > 0 {
+		reloader = newCertReloader(configuration.CertFile, configuration.KeyFile)
+		tlsPacketConnConfigs, tlsListenerConfigs := tlsAndDTLSConfigs(reloader, relayAddressGenerator)
+		packetConnConfigs = append(packetConnConfigs, tlsPacketConnConfigs...)
+		listenerConfigs = append(listenerConfigs, tlsListenerConfigs...)
+	}
+
+	if len(packetConnConfigs) == 0 && len(listenerConfigs) == 0 {
+		log.Panic("socketType must be tcp or udp or both(tcp,udp)")
+	}
+
+	serverConfig := turn.ServerConfig{
+		Realm:             configuration.Realm,
+		AuthHandler:       buildAuthHandler(usersMap),
+		PacketConnConfigs: packetConnConfigs,
+		ListenerConfigs:   listenerConfigs,
+	}
+	if metricsCollector != nil {
+		serverConfig.MetricsCollector = metricsCollector
+	}
+	if len(configuration.UsersQuota) > 0 {
+		serverConfig.QuotaHandler = buildQuotaHandler()
+	}
+
+	s, err := turn.NewServer(serverConfig)
+
+	if err != nil {
+		log.Panic(err)
+	}
+
+	log.Printf("create TURN server success\n")
+
+	// Block until user sends SIGINT or SIGTERM, reloading the turns:
+	// certificate from disk in place whenever SIGHUP arrives, same as
+	// singleThreadSocket.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigs {
+		if sig == syscall.SIGHUP && reloader != nil {
+			reloader.reload()
+			continue
+		}
+		break
+	}
+
+	if err = s.Close(); err != nil {
+		log.Panic(err)
+	}
 }
@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reuseportAvailable reports whether this platform can bind multiple
+// sockets to the same address/port via SO_REUSEPORT.
+const reuseportAvailable = true
+
+// reuseportControl is installed as the Control callback on a net.ListenConfig
+// so that every socket it creates is allowed to share its port with other
+// sockets bound the same way. The kernel then load-balances incoming
+// packets/connections across all of them.
+func reuseportControl(_, _ string, c syscall.RawConn) error {
+	var setErr error
+	err := c.Control(func(fd uintptr) {
+		setErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return setErr
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pion/turn/v3"
+	"github.com/pion/turn/v3/client"
+)
+
+// TestMultiThreadSocketBind verifies that N workers can each bind their own
+// SO_REUSEPORT socket to the same address/port concurrently.
+func TestMultiThreadSocketBind(t *testing.T) {
+	const threadNum = 4
+	listenConfig := net.ListenConfig{Control: reuseportControl}
+	addr := "127.0.0.1:0"
+
+	// All but the first bind must reuse the first's port, so resolve it once
+	// the first socket is up.
+	first, err := listenConfig.ListenPacket(context.Background(), "udp4", addr)
+	if err != nil {
+		t.Fatalf("failed to bind first UDP socket: %v", err)
+	}
+	defer first.Close() //nolint:errcheck
+
+	boundAddr := first.LocalAddr().String()
+
+	conns := make([]net.PacketConn, 0, threadNum-1)
+	defer func() {
+		for _, c := range conns {
+			c.Close() //nolint:errcheck
+		}
+	}()
+
+	for i := 1; i < threadNum; i++ {
+		conn, err := listenConfig.ListenPacket(context.Background(), "udp4", boundAddr)
+		if err != nil {
+			t.Fatalf("failed to bind UDP socket %d/%d on %s: %v", i+1, threadNum, boundAddr, err)
+		}
+		conns = append(conns, conn)
+	}
+}
+
+// TestMultiThreadSocketAllocation verifies that a client can successfully
+// create a TURN allocation against a server built from several
+// SO_REUSEPORT-bound PacketConnConfigs, regardless of which worker socket
+// the kernel hands its traffic to.
+func TestMultiThreadSocketAllocation(t *testing.T) {
+	const threadNum = 3
+	listenConfig := net.ListenConfig{Control: reuseportControl}
+
+	var packetConnConfigs []turn.PacketConnConfig
+	var conns []net.PacketConn
+	defer func() {
+		for _, c := range conns {
+			c.Close() //nolint:errcheck
+		}
+	}()
+
+	addr := "127.0.0.1:0"
+	for i := 0; i < threadNum; i++ {
+		conn, err := listenConfig.ListenPacket(context.Background(), "udp4", addr)
+		if err != nil {
+			t.Fatalf("failed to bind UDP socket %d/%d: %v", i+1, threadNum, err)
+		}
+		if i == 0 {
+			addr = conn.LocalAddr().String()
+		}
+		conns = append(conns, conn)
+		packetConnConfigs = append(packetConnConfigs, turn.PacketConnConfig{
+			PacketConn: conn,
+			RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+				RelayAddress: net.ParseIP("127.0.0.1"),
+				Address:      "0.0.0.0",
+			},
+		})
+	}
+
+	usersMap := map[string][]byte{
+		"user": turn.GenerateAuthKey("user", "test-realm", "pass"),
+	}
+
+	s, err := turn.NewServer(turn.ServerConfig{
+		Realm: "test-realm",
+		AuthHandler: func(username string, realm string, srcAddr net.Addr) ([]byte, bool) {
+			key, ok := usersMap[username]
+			return key, ok
+		},
+		PacketConnConfigs: packetConnConfigs,
+	})
+	if err != nil {
+		t.Fatalf("failed to start TURN server: %v", err)
+	}
+	defer s.Close() //nolint:errcheck
+
+	serverAddr := conns[0].LocalAddr().String()
+
+	// Each iteration opens a fresh client UDP socket (a distinct 5-tuple), so
+	// the kernel's SO_REUSEPORT hash is free to land the Allocate request on
+	// any of the threadNum worker sockets above; every one of them must be
+	// able to serve a real TURN allocation on its own.
+	for i := 0; i < threadNum*2; i++ {
+		clientConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("client %d: failed to open local socket: %v", i, err)
+		}
+
+		turnClient, err := client.NewClient(&client.ClientConfig{
+			STUNServerAddr: serverAddr,
+			TURNServerAddr: serverAddr,
+			Conn:           clientConn,
+			Username:       "user",
+			Password:       "pass",
+			Realm:          "test-realm",
+		})
+		if err != nil {
+			clientConn.Close() //nolint:errcheck
+			t.Fatalf("client %d: failed to construct TURN client: %v", i, err)
+		}
+
+		if err := turnClient.Listen(); err != nil {
+			turnClient.Close()
+			t.Fatalf("client %d: failed to start listening: %v", i, err)
+		}
+
+		relayConn, err := turnClient.Allocate()
+		if err != nil {
+			turnClient.Close()
+			t.Fatalf("client %d: failed to allocate a TURN relay: %v", i, err)
+		}
+
+		relayConn.Close() //nolint:errcheck
+		turnClient.Close()
+	}
+}
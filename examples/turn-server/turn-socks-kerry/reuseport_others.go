@@ -0,0 +1,22 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reuseportAvailable reports whether this platform can bind multiple
+// sockets to the same address/port via SO_REUSEPORT.
+const reuseportAvailable = false
+
+var errReuseportUnsupported = errors.New("multi-thread mode requires SO_REUSEPORT, which is not supported on this platform; use single-thread mode instead")
+
+// reuseportControl always fails on platforms without SO_REUSEPORT (e.g. Windows).
+func reuseportControl(_, _ string, _ syscall.RawConn) error {
+	return errReuseportUnsupported
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/turn/v3"
+)
+
+// certReloader keeps the currently-served TLS/DTLS certificate behind an
+// atomic.Value so it can be swapped in place (e.g. on SIGHUP) without
+// tearing down the listeners that reference it through GetCertificate.
+type certReloader struct {
+	certFile, keyFile string
+	current           atomic.Value // holds *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) *certReloader {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	r.reload()
+	return r
+}
+
+// reload re-reads the certificate and key from disk and atomically swaps
+// them in. Listeners already in use immediately start presenting the new
+// certificate on their next handshake.
+func (r *certReloader) reload() {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		log.Printf("failed to reload TLS certificate from %s/%s: %s\n", r.certFile, r.keyFile, err)
+		return
+	}
+	r.current.Store(&cert)
+	log.Printf("reloaded TLS certificate from %s\n", r.certFile)
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.current.Load().(*tls.Certificate), nil
+}
+
+// getDTLSCertificate is the same lookup as getCertificate, against dtls.Config's
+// distinct (but identically shaped) ClientHelloInfo type.
+func (r *certReloader) getDTLSCertificate(*dtls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.current.Load().(*tls.Certificate), nil
+}
+
+// tlsAndDTLSConfigs opens a TLS-over-TCP listener and a DTLS-over-UDP
+// listener on configuration.TLSPort. The DTLS listener carries TURN traffic
+// for turns: URIs alongside the plain turn: listeners. The TLS listener is
+// returned as a ListenerConfig, on which turn.Server accepts and closes
+// connections without serving TURN over them.
+func tlsAndDTLSConfigs(reloader *certReloader, relayAddressGenerator turn.RelayAddressGenerator) ([]turn.PacketConnConfig, []turn.ListenerConfig) {
+	addr := "0.0.0.0:" + strconv.Itoa(configuration.TLSPort)
+
+	tcpListener, err := net.Listen("tcp4", addr)
+	if err != nil {
+		log.Panicf("Failed to create TLS TURN server listener: %s", err)
+	}
+	tlsListener := tls.NewListener(tcpListener, &tls.Config{
+		GetCertificate: reloader.getCertificate,
+	})
+	log.Printf("create TLS TURN server listener at port %d\n", configuration.TLSPort)
+
+	dtlsListener, err := dtls.Listen("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: configuration.TLSPort}, &dtls.Config{
+		GetCertificate: reloader.getDTLSCertificate,
+	})
+	if err != nil {
+		log.Panicf("Failed to create DTLS TURN server listener: %s", err)
+	}
+	dtlsPacketConn := newDTLSPacketConn(dtlsListener)
+	log.Printf("create DTLS TURN server listener at port %d\n", configuration.TLSPort)
+
+	packetConnConfigs := []turn.PacketConnConfig{
+		{
+			PacketConn:            dtlsPacketConn,
+			RelayAddressGenerator: relayAddressGenerator,
+		},
+	}
+	listenerConfigs := []turn.ListenerConfig{
+		{
+			Listener:              tlsListener,
+			RelayAddressGenerator: relayAddressGenerator,
+		},
+	}
+
+	return packetConnConfigs, listenerConfigs
+}
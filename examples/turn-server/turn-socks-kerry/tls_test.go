@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/turn/v3"
+	"github.com/pion/turn/v3/client"
+)
+
+// writeSelfSignedCert writes a throwaway self-signed certificate/key pair to
+// disk for use by certReloader, returning their paths.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	certOut, err := os.CreateTemp(t.TempDir(), "cert-*.pem")
+	if err != nil {
+		t.Fatalf("failed to create cert file: %v", err)
+	}
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("failed to write cert: %v", err)
+	}
+	certOut.Close() //nolint:errcheck
+
+	keyOut, err := os.CreateTemp(t.TempDir(), "key-*.pem")
+	if err != nil {
+		t.Fatalf("failed to create key file: %v", err)
+	}
+	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	keyOut.Close() //nolint:errcheck
+
+	return certOut.Name(), keyOut.Name()
+}
+
+// TestTLSAndDTLSListenersAcceptHandshakes confirms that the turns: listeners
+// built by tlsAndDTLSConfigs accept a TLS client over TCP and a DTLS client
+// over UDP using the certificate served through certReloader.
+func TestTLSAndDTLSListenersAcceptHandshakes(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	reloader := newCertReloader(certFile, keyFile)
+
+	relayAddressGenerator := &turn.RelayAddressGeneratorStatic{
+		RelayAddress: net.ParseIP("127.0.0.1"),
+		Address:      "0.0.0.0",
+	}
+
+	configuration.TLSPort = 0 // overridden below by tlsAndDTLSConfigsOnPort before the listeners open
+
+	packetConnConfigs, listenerConfigs := tlsAndDTLSConfigsOnPort(t, reloader, relayAddressGenerator)
+	defer func() {
+		listenerConfigs[0].Listener.Close()   //nolint:errcheck
+		packetConnConfigs[0].PacketConn.Close() //nolint:errcheck
+	}()
+
+	// tls.Listener.Accept hands back the raw connection without handshaking;
+	// the handshake only happens once something reads or writes it, so drive
+	// it from a server-side goroutine here.
+	go func() {
+		conn, err := listenerConfigs[0].Listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() //nolint:errcheck
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			_ = tlsConn.Handshake()
+		}
+	}()
+
+	tcpAddr := listenerConfigs[0].Listener.Addr().(*net.TCPAddr)
+	tlsConn, err := tls.Dial("tcp4", tcpAddr.String(), &tls.Config{InsecureSkipVerify: true}) //nolint:gosec
+	if err != nil {
+		t.Fatalf("TLS client failed to handshake: %v", err)
+	}
+	tlsConn.Close() //nolint:errcheck
+
+	udpAddr, err := net.ResolveUDPAddr("udp4", packetConnConfigs[0].PacketConn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to resolve DTLS listener address: %v", err)
+	}
+	dtlsConn, err := dtls.Dial("udp4", udpAddr, &dtls.Config{InsecureSkipVerify: true}) //nolint:gosec
+	if err != nil {
+		t.Fatalf("DTLS client failed to handshake: %v", err)
+	}
+	dtlsConn.Close() //nolint:errcheck
+}
+
+// connPacketConn adapts a net.Conn already connected to the TURN server (a
+// dtls.Conn here) into the net.PacketConn that client.ClientConfig expects,
+// since a connected DTLS session has no notion of a destination address per
+// write.
+type connPacketConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *connPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, err := c.Conn.Read(p)
+	return n, c.remote, err
+}
+
+func (c *connPacketConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	return c.Conn.Write(p)
+}
+
+// TestTURNAllocationOverDTLS confirms that a TURN client can authenticate
+// and create a relay allocation over a turns: (DTLS) listener, not just the
+// plain UDP listener the rest of this package's tests exercise.
+func TestTURNAllocationOverDTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	reloader := newCertReloader(certFile, keyFile)
+
+	relayAddressGenerator := &turn.RelayAddressGeneratorStatic{
+		RelayAddress: net.ParseIP("127.0.0.1"),
+		Address:      "0.0.0.0",
+	}
+
+	configuration.TLSPort = 0
+	packetConnConfigs, listenerConfigs := tlsAndDTLSConfigsOnPort(t, reloader, relayAddressGenerator)
+	defer func() {
+		listenerConfigs[0].Listener.Close()     //nolint:errcheck
+		packetConnConfigs[0].PacketConn.Close() //nolint:errcheck
+	}()
+
+	usersMap := map[string][]byte{
+		"user": turn.GenerateAuthKey("user", "test-realm", "pass"),
+	}
+	s, err := turn.NewServer(turn.ServerConfig{
+		Realm: "test-realm",
+		AuthHandler: func(username string, realm string, srcAddr net.Addr) ([]byte, bool) {
+			key, ok := usersMap[username]
+			return key, ok
+		},
+		PacketConnConfigs: packetConnConfigs,
+	})
+	if err != nil {
+		t.Fatalf("failed to start TURN server: %v", err)
+	}
+	defer s.Close() //nolint:errcheck
+
+	serverAddr, err := net.ResolveUDPAddr("udp4", packetConnConfigs[0].PacketConn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to resolve DTLS listener address: %v", err)
+	}
+	dtlsConn, err := dtls.Dial("udp4", serverAddr, &dtls.Config{InsecureSkipVerify: true}) //nolint:gosec
+	if err != nil {
+		t.Fatalf("DTLS client failed to handshake: %v", err)
+	}
+	defer dtlsConn.Close() //nolint:errcheck
+
+	turnClient, err := client.NewClient(&client.ClientConfig{
+		STUNServerAddr: serverAddr.String(),
+		TURNServerAddr: serverAddr.String(),
+		Conn:           &connPacketConn{Conn: dtlsConn, remote: serverAddr},
+		Username:       "user",
+		Password:       "pass",
+		Realm:          "test-realm",
+	})
+	if err != nil {
+		t.Fatalf("failed to construct TURN client: %v", err)
+	}
+
+	if err := turnClient.Listen(); err != nil {
+		t.Fatalf("failed to start listening: %v", err)
+	}
+	defer turnClient.Close() //nolint:errcheck
+
+	relayConn, err := turnClient.Allocate()
+	if err != nil {
+		t.Fatalf("failed to allocate a TURN relay over DTLS: %v", err)
+	}
+	defer relayConn.Close() //nolint:errcheck
+}
+
+// tlsAndDTLSConfigsOnPort picks a free port, points the global configuration
+// at it, and delegates to tlsAndDTLSConfigs so the test doesn't race other
+// tests over a fixed port.
+func tlsAndDTLSConfigsOnPort(t *testing.T, reloader *certReloader, relayAddressGenerator turn.RelayAddressGenerator) ([]turn.PacketConnConfig, []turn.ListenerConfig) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to pick a free port: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close() //nolint:errcheck
+
+	configuration.TLSPort = port
+	return tlsAndDTLSConfigs(reloader, relayAddressGenerator)
+}
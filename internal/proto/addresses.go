@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import "github.com/pion/stun/v2"
+
+// RelayedAddress and PeerAddress are XOR-RELAYED-ADDRESS and XOR-PEER-ADDRESS
+// (RFC 5766 sections 14.5, 14.3). Both encode exactly like XOR-MAPPED-ADDRESS
+// (RFC 5389 section 15.2), so they reuse stun.XORMappedAddress's generic
+// AddToAs/GetFromAs rather than reimplementing the XOR-address codec.
+type (
+	RelayedAddress struct{ stun.XORMappedAddress }
+	PeerAddress    struct{ stun.XORMappedAddress }
+)
+
+// AddTo adds XOR-RELAYED-ADDRESS to m.
+func (a RelayedAddress) AddTo(m *stun.Message) error {
+	return a.XORMappedAddress.AddToAs(m, stun.AttrXORRelayedAddress)
+}
+
+// GetFrom gets XOR-RELAYED-ADDRESS from m.
+func (a *RelayedAddress) GetFrom(m *stun.Message) error {
+	return a.XORMappedAddress.GetFromAs(m, stun.AttrXORRelayedAddress)
+}
+
+// AddTo adds XOR-PEER-ADDRESS to m.
+func (a PeerAddress) AddTo(m *stun.Message) error {
+	return a.XORMappedAddress.AddToAs(m, stun.AttrXORPeerAddress)
+}
+
+// GetFrom gets XOR-PEER-ADDRESS from m.
+func (a *PeerAddress) GetFrom(m *stun.Message) error {
+	return a.XORMappedAddress.GetFromAs(m, stun.AttrXORPeerAddress)
+}
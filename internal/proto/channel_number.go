@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/stun/v2"
+)
+
+// MinChannelNumber and MaxChannelNumber bound the CHANNEL-NUMBER values a
+// ChannelBind request may legally request (RFC 5766 section 11).
+const (
+	MinChannelNumber uint16 = 0x4000
+	MaxChannelNumber uint16 = 0x7FFE
+)
+
+// ErrInvalidChannelNumber is returned by GetFrom when a CHANNEL-NUMBER falls
+// outside [MinChannelNumber, MaxChannelNumber].
+var ErrInvalidChannelNumber = errors.New("channel number out of the valid 0x4000-0x7FFE range")
+
+// ChannelNumber represents the CHANNEL-NUMBER attribute: a 16-bit channel
+// number followed by 2 reserved bytes.
+type ChannelNumber uint16
+
+// AddTo adds CHANNEL-NUMBER to m.
+func (n ChannelNumber) AddTo(m *stun.Message) error {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint16(v, uint16(n))
+	m.Add(stun.AttrChannelNumber, v)
+	return nil
+}
+
+// GetFrom gets CHANNEL-NUMBER from m, rejecting a value outside the valid
+// range.
+func (n *ChannelNumber) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrChannelNumber)
+	if err != nil {
+		return err
+	}
+	if err := stun.CheckSize(stun.AttrChannelNumber, len(v), 4); err != nil {
+		return err
+	}
+
+	channel := binary.BigEndian.Uint16(v)
+	if channel < MinChannelNumber || channel > MaxChannelNumber {
+		return ErrInvalidChannelNumber
+	}
+
+	*n = ChannelNumber(channel)
+	return nil
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// channelDataHeaderSize is the 2-byte channel number plus the 2-byte length
+// field that precedes a ChannelData message's application data (RFC 5766
+// section 11.4).
+const channelDataHeaderSize = 4
+
+var (
+	// ErrChannelDataTooShort is returned by ParseChannelData when buf is
+	// smaller than channelDataHeaderSize.
+	ErrChannelDataTooShort = errors.New("buffer too short to contain a ChannelData header")
+	// ErrChannelDataLengthMismatch is returned by ParseChannelData when the
+	// length field doesn't match the application data actually present.
+	ErrChannelDataLengthMismatch = errors.New("channel data length field does not match the data present")
+)
+
+// IsChannelData reports whether the first two bytes of buf fall in the
+// bound channel number range, distinguishing a ChannelData message from a
+// STUN message on the same socket: every STUN message type's most
+// significant two bits are 0, while every valid channel number's are 01
+// (RFC 5766 section 11).
+func IsChannelData(buf []byte) bool {
+	if len(buf) < channelDataHeaderSize {
+		return false
+	}
+	channel := binary.BigEndian.Uint16(buf)
+	return channel >= MinChannelNumber && channel <= MaxChannelNumber
+}
+
+// EncodeChannelData prepends the ChannelData header for channel to data,
+// returning the wire-ready message. On a stream transport the result must
+// be padded to a 4-byte boundary before the next message; callers sending
+// over UDP, where each message is its own packet, need not pad.
+func EncodeChannelData(channel ChannelNumber, data []byte) []byte {
+	buf := make([]byte, channelDataHeaderSize+len(data))
+	binary.BigEndian.PutUint16(buf, uint16(channel))
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(data)))
+	copy(buf[channelDataHeaderSize:], data)
+	return buf
+}
+
+// ParseChannelData reads the channel number and application data out of a
+// single ChannelData message. It is intended for UDP, where each message is
+// a whole datagram and any bytes past the declared length are padding added
+// by the sender; on TCP, where messages are concatenated, callers must split
+// on the length field themselves before calling this.
+func ParseChannelData(buf []byte) (ChannelNumber, []byte, error) {
+	if len(buf) < channelDataHeaderSize {
+		return 0, nil, ErrChannelDataTooShort
+	}
+
+	channel := ChannelNumber(binary.BigEndian.Uint16(buf))
+	length := int(binary.BigEndian.Uint16(buf[2:]))
+	if channelDataHeaderSize+length > len(buf) {
+		return 0, nil, ErrChannelDataLengthMismatch
+	}
+
+	return channel, buf[channelDataHeaderSize : channelDataHeaderSize+length], nil
+}
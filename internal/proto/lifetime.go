@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package proto implements the STUN attributes and message framing that RFC
+// 5766 adds on top of pion/stun's base RFC 5389 support: LIFETIME,
+// REQUESTED-TRANSPORT, CHANNEL-NUMBER, DATA, and the ChannelData framing
+// used once a channel is bound.
+package proto
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pion/stun/v2"
+)
+
+// DefaultLifetime is the lifetime an Allocate or Refresh request gets when
+// it carries no Lifetime attribute of its own (RFC 5766 section 6.2).
+const DefaultLifetime = 10 * time.Minute
+
+// Lifetime represents the LIFETIME attribute, a 32-bit unsigned number of
+// seconds.
+type Lifetime struct {
+	Duration time.Duration
+}
+
+// AddTo adds LIFETIME to m.
+func (l Lifetime) AddTo(m *stun.Message) error {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint32(v, uint32(l.Duration.Seconds()))
+	m.Add(stun.AttrLifetime, v)
+	return nil
+}
+
+// GetFrom gets LIFETIME from m.
+func (l *Lifetime) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrLifetime)
+	if err != nil {
+		return err
+	}
+	if err := stun.CheckSize(stun.AttrLifetime, len(v), 4); err != nil {
+		return err
+	}
+	l.Duration = time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+	return nil
+}
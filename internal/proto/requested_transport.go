@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import "github.com/pion/stun/v2"
+
+// ProtoUDP is the only REQUESTED-TRANSPORT protocol number a TURN server is
+// required to support (RFC 5766 section 14.7); IANA assigns 17 to UDP.
+const ProtoUDP byte = 17
+
+// RequestedTransport represents the REQUESTED-TRANSPORT attribute: a
+// protocol number followed by 3 reserved bytes.
+type RequestedTransport struct {
+	Protocol byte
+}
+
+// AddTo adds REQUESTED-TRANSPORT to m.
+func (t RequestedTransport) AddTo(m *stun.Message) error {
+	m.Add(stun.AttrRequestedTransport, []byte{t.Protocol, 0, 0, 0})
+	return nil
+}
+
+// GetFrom gets REQUESTED-TRANSPORT from m.
+func (t *RequestedTransport) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrRequestedTransport)
+	if err != nil {
+		return err
+	}
+	if err := stun.CheckSize(stun.AttrRequestedTransport, len(v), 4); err != nil {
+		return err
+	}
+	t.Protocol = v[0]
+	return nil
+}
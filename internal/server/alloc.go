@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/turn/v3/internal/proto"
+)
+
+// permissionLifetime and channelLifetime are how long a CreatePermission or
+// ChannelBind stays installed without being refreshed (RFC 5766 sections
+// 9.1, 11.2).
+const (
+	permissionLifetime = 5 * time.Minute
+	channelLifetime    = 10 * time.Minute
+)
+
+// Allocation is one relayed socket bound to an authenticated user, created
+// in response to an Allocate request and torn down on Refresh(0) or expiry.
+// It is what the data path consults before relaying a frame in either
+// direction.
+type Allocation struct {
+	Username  string
+	Realm     string
+	Transport Transport
+	SrcAddr   net.Addr
+	RelayConn net.PacketConn
+
+	metrics MetricsCollector
+	quota   *allocationQuota
+
+	permissionsMu sync.Mutex
+	permissions   map[string]time.Time // peer IP (net.IP.String()) -> expiry
+
+	channelsMu    sync.Mutex
+	channelToPeer map[proto.ChannelNumber]net.Addr
+	peerToChannel map[string]proto.ChannelNumber
+	channelExpiry map[proto.ChannelNumber]time.Time
+
+	createdAt time.Time
+}
+
+// Relayed records n bytes that were actually forwarded in the given
+// direction; inbound is true for client->relay traffic.
+func (a *Allocation) Relayed(inbound bool, n int) {
+	a.metrics.BytesRelayed(a.Username, a.Transport, inbound, n)
+}
+
+// AllowRelay reports whether n bytes may be forwarded right now under this
+// allocation's Quota, reporting the Throttled metric when they may not. The
+// data path must check this before forwarding every frame in either
+// direction and drop the frame on false.
+func (a *Allocation) AllowRelay(n int) bool {
+	if a.quota.AllowRelay(n) {
+		return true
+	}
+	a.metrics.Throttled(a.Username, a.Transport)
+	return false
+}
+
+// CreatePermission installs (or refreshes) permission for peerIP to exchange
+// data through this allocation and reports the PermissionCreated metric.
+func (a *Allocation) CreatePermission(peerIP net.IP) {
+	a.permissionsMu.Lock()
+	a.permissions[peerIP.String()] = time.Now().Add(permissionLifetime)
+	a.permissionsMu.Unlock()
+
+	a.metrics.PermissionCreated(a.Username)
+}
+
+// HasPermission reports whether peerIP currently has a live permission on
+// this allocation; the Send and ChannelData data paths must check this
+// before relaying to a peer (RFC 5766 section 9.1).
+func (a *Allocation) HasPermission(peerIP net.IP) bool {
+	a.permissionsMu.Lock()
+	defer a.permissionsMu.Unlock()
+
+	expiry, ok := a.permissions[peerIP.String()]
+	return ok && time.Now().Before(expiry)
+}
+
+// BindChannel installs (or refreshes) a channel number bound to peerAddr,
+// implicitly creating the permission that ChannelBind requires (RFC 5766
+// section 11.2), and reports the ChannelBindCreated metric. It returns
+// false if channel is already bound to a different peer.
+func (a *Allocation) BindChannel(channel proto.ChannelNumber, peerAddr net.Addr) bool {
+	a.channelsMu.Lock()
+	if existing, ok := a.channelToPeer[channel]; ok && existing.String() != peerAddr.String() {
+		a.channelsMu.Unlock()
+		return false
+	}
+	a.channelToPeer[channel] = peerAddr
+	a.peerToChannel[peerAddr.String()] = channel
+	a.channelExpiry[channel] = time.Now().Add(channelLifetime)
+	a.channelsMu.Unlock()
+
+	if peerIP, _, err := net.SplitHostPort(peerAddr.String()); err == nil {
+		a.CreatePermission(net.ParseIP(peerIP))
+	}
+	a.metrics.ChannelBindCreated(a.Username)
+	return true
+}
+
+// PeerForChannel returns the peer address bound to channel, if the binding
+// is still live.
+func (a *Allocation) PeerForChannel(channel proto.ChannelNumber) (net.Addr, bool) {
+	a.channelsMu.Lock()
+	defer a.channelsMu.Unlock()
+
+	peer, ok := a.channelToPeer[channel]
+	if !ok || time.Now().After(a.channelExpiry[channel]) {
+		return nil, false
+	}
+	return peer, true
+}
+
+// ChannelForPeer returns the channel number bound to peerAddr, if any, so
+// the relay->client data path can send ChannelData instead of a Data
+// indication once a channel is bound.
+func (a *Allocation) ChannelForPeer(peerAddr net.Addr) (proto.ChannelNumber, bool) {
+	a.channelsMu.Lock()
+	defer a.channelsMu.Unlock()
+
+	channel, ok := a.peerToChannel[peerAddr.String()]
+	if !ok || time.Now().After(a.channelExpiry[channel]) {
+		return 0, false
+	}
+	return channel, true
+}
+
+// AllocationManager tracks every live Allocation, keyed by the client's
+// 5-tuple, so the data path and eventual cleanup share one place that
+// reports into MetricsCollector and EventHandler.
+type AllocationManager struct {
+	mu          sync.Mutex
+	allocations map[string]*Allocation
+
+	metrics MetricsCollector
+	events  EventHandler
+
+	quotaHandler QuotaHandler
+	quotasMu     sync.Mutex
+	quotas       map[string]*allocationQuota
+}
+
+// NewAllocationManager creates an AllocationManager. A nil metrics or
+// events defaults to its respective Noop implementation. A nil quotaHandler
+// leaves every allocation unrestricted.
+func NewAllocationManager(metrics MetricsCollector, events EventHandler, quotaHandler QuotaHandler) *AllocationManager {
+	if metrics == nil {
+		metrics = NoopMetricsCollector{}
+	}
+	if events == nil {
+		events = NoopEventHandler{}
+	}
+
+	return &AllocationManager{
+		allocations:  make(map[string]*Allocation),
+		metrics:      metrics,
+		events:       events,
+		quotaHandler: quotaHandler,
+		quotas:       make(map[string]*allocationQuota),
+	}
+}
+
+// quotaFor returns the allocationQuota tracking username's usage, consulting
+// quotaHandler and caching the result the first time username is seen so
+// that MonthlyByteCap accumulates across that user's allocations rather than
+// resetting with each one. Returns nil when quotaHandler is unset.
+func (m *AllocationManager) quotaFor(username, realm string) *allocationQuota {
+	if m.quotaHandler == nil {
+		return nil
+	}
+
+	m.quotasMu.Lock()
+	defer m.quotasMu.Unlock()
+
+	q, ok := m.quotas[username]
+	if !ok {
+		q = newAllocationQuota(m.quotaHandler(username, realm))
+		m.quotas[username] = q
+	}
+	return q
+}
+
+// MonthlyCapExceeded reports whether username has already exhausted its
+// monthly byte cap, for rejecting a new Allocate with
+// CodeAllocQuotaReached instead of creating it.
+func (m *AllocationManager) MonthlyCapExceeded(username, realm string) bool {
+	return m.quotaFor(username, realm).MonthlyCapExceeded()
+}
+
+// Create registers a new allocation for key and reports AllocationCreated
+// plus an AllocationStarted event.
+func (m *AllocationManager) Create(
+	key, username, realm string, transport Transport, srcAddr net.Addr, relayConn net.PacketConn,
+) *Allocation {
+	alloc := &Allocation{
+		Username:      username,
+		Realm:         realm,
+		Transport:     transport,
+		SrcAddr:       srcAddr,
+		RelayConn:     relayConn,
+		metrics:       m.metrics,
+		quota:         m.quotaFor(username, realm),
+		permissions:   make(map[string]time.Time),
+		channelToPeer: make(map[proto.ChannelNumber]net.Addr),
+		peerToChannel: make(map[string]proto.ChannelNumber),
+		channelExpiry: make(map[proto.ChannelNumber]time.Time),
+		createdAt:     time.Now(),
+	}
+
+	m.mu.Lock()
+	m.allocations[key] = alloc
+	m.mu.Unlock()
+
+	m.metrics.AllocationCreated(username, transport)
+	m.events.AllocationStarted(AllocationEvent{
+		Username:  username,
+		Realm:     realm,
+		Transport: transport,
+		SrcAddr:   srcAddr,
+		RelayAddr: relayConn.LocalAddr(),
+		Timestamp: alloc.createdAt,
+	})
+
+	return alloc
+}
+
+// Get returns the allocation registered for key, if one is still live.
+func (m *AllocationManager) Get(key string) (*Allocation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alloc, ok := m.allocations[key]
+	return alloc, ok
+}
+
+// Destroy tears down the allocation registered for key, closing its relay
+// socket and reporting AllocationDestroyed plus an AllocationStopped event.
+// It is a no-op if key is not (or no longer) registered.
+func (m *AllocationManager) Destroy(key string) {
+	m.mu.Lock()
+	alloc, ok := m.allocations[key]
+	if ok {
+		delete(m.allocations, key)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	_ = alloc.RelayConn.Close()
+
+	lifetime := time.Since(alloc.createdAt)
+	m.metrics.AllocationDestroyed(alloc.Username, alloc.Transport, lifetime)
+	m.events.AllocationStopped(AllocationEvent{
+		Username:  alloc.Username,
+		Realm:     alloc.Realm,
+		Transport: alloc.Transport,
+		SrcAddr:   alloc.SrcAddr,
+		RelayAddr: alloc.RelayConn.LocalAddr(),
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeMetrics struct {
+	created    int
+	destroyed  int
+	relayed    int
+	throttled  int
+	lastReason FailureReason
+}
+
+func (f *fakeMetrics) AllocationCreated(string, Transport)                  { f.created++ }
+func (f *fakeMetrics) AllocationDestroyed(string, Transport, time.Duration) { f.destroyed++ }
+func (f *fakeMetrics) BytesRelayed(string, Transport, bool, int)            { f.relayed++ }
+func (f *fakeMetrics) ChannelBindCreated(string)                            {}
+func (f *fakeMetrics) PermissionCreated(string)                             {}
+func (f *fakeMetrics) AuthFailure(reason FailureReason)                     { f.lastReason = reason }
+func (f *fakeMetrics) Throttled(string, Transport)                          { f.throttled++ }
+
+type fakeEvents struct {
+	started int
+	stopped int
+}
+
+func (f *fakeEvents) AllocationStarted(AllocationEvent) { f.started++ }
+func (f *fakeEvents) AllocationStopped(AllocationEvent) { f.stopped++ }
+
+func newTestRelayConn(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to open relay socket: %v", err)
+	}
+	return conn
+}
+
+func TestAllocationManagerCreateAndDestroy(t *testing.T) {
+	metrics := &fakeMetrics{}
+	events := &fakeEvents{}
+	manager := NewAllocationManager(metrics, events, nil)
+
+	relayConn := newTestRelayConn(t)
+	srcAddr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345}
+
+	alloc := manager.Create("client-key", "alice", "example.org", TransportUDP, srcAddr, relayConn)
+	if metrics.created != 1 || events.started != 1 {
+		t.Fatalf("expected Create to report one AllocationCreated and one AllocationStarted, got %+v %+v", metrics, events)
+	}
+
+	if got, ok := manager.Get("client-key"); !ok || got != alloc {
+		t.Fatalf("expected Get to find the allocation just created")
+	}
+
+	manager.Destroy("client-key")
+	if metrics.destroyed != 1 || events.stopped != 1 {
+		t.Fatalf("expected Destroy to report one AllocationDestroyed and one AllocationStopped, got %+v %+v", metrics, events)
+	}
+
+	if _, ok := manager.Get("client-key"); ok {
+		t.Fatalf("expected Get to report no allocation after Destroy")
+	}
+
+	// A second Destroy for the same (now absent) key must be a no-op.
+	manager.Destroy("client-key")
+	if metrics.destroyed != 1 || events.stopped != 1 {
+		t.Fatalf("expected Destroy on an unknown key to be a no-op, got %+v %+v", metrics, events)
+	}
+}
+
+func TestAllocationManagerDefaultsToNoop(t *testing.T) {
+	manager := NewAllocationManager(nil, nil, nil)
+	alloc := manager.Create("key", "alice", "example.org", TransportUDP, &net.UDPAddr{}, newTestRelayConn(t))
+
+	alloc.Relayed(true, 10)
+	if !alloc.AllowRelay(1 << 20) {
+		t.Fatalf("expected AllowRelay to be unlimited with no QuotaHandler configured")
+	}
+	manager.Destroy("key")
+}
+
+func TestAllocationManagerEnforcesQuota(t *testing.T) {
+	metrics := &fakeMetrics{}
+	quotaHandler := func(string, string) Quota { return Quota{BytesPerSecond: 10} }
+	manager := NewAllocationManager(metrics, nil, quotaHandler)
+
+	alloc := manager.Create("key", "alice", "example.org", TransportUDP, &net.UDPAddr{}, newTestRelayConn(t))
+
+	if !alloc.AllowRelay(10) {
+		t.Fatalf("expected the first 10 bytes to fit the bucket")
+	}
+	if alloc.AllowRelay(1) {
+		t.Fatalf("expected the bucket to be empty immediately after spending its capacity")
+	}
+	if metrics.throttled != 1 {
+		t.Fatalf("expected a rejected AllowRelay to report exactly one Throttled, got %d", metrics.throttled)
+	}
+}
+
+func TestAllocationManagerMonthlyCapExceeded(t *testing.T) {
+	quotaHandler := func(string, string) Quota { return Quota{MonthlyByteCap: 100} }
+	manager := NewAllocationManager(nil, nil, quotaHandler)
+
+	if manager.MonthlyCapExceeded("alice", "example.org") {
+		t.Fatalf("expected a fresh quota to not report its cap exceeded")
+	}
+
+	alloc := manager.Create("key", "alice", "example.org", TransportUDP, &net.UDPAddr{}, newTestRelayConn(t))
+	if !alloc.AllowRelay(100) {
+		t.Fatalf("expected spending exactly up to the cap to be allowed")
+	}
+
+	if !manager.MonthlyCapExceeded("alice", "example.org") {
+		t.Fatalf("expected the cap to be reported exceeded after it was reached, even from a second Allocate for the same user")
+	}
+}
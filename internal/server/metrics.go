@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"net"
+	"time"
+)
+
+// Transport identifies which socket type an allocation or relayed packet
+// belongs to, used to label metrics and events.
+type Transport string
+
+const (
+	TransportUDP Transport = "udp"
+	TransportTCP Transport = "tcp"
+)
+
+// FailureReason classifies why AuthenticateRequest rejected a request, used
+// to label the AuthFailure metric.
+type FailureReason string
+
+const (
+	FailureReasonStaleNonce   FailureReason = "stale-nonce"
+	FailureReasonUnknownUser  FailureReason = "unknown-user"
+	FailureReasonBadIntegrity FailureReason = "bad-integrity"
+)
+
+// MetricsCollector receives counters and gauges from across the server.
+// Request.Metrics defaults to NoopMetricsCollector{} when unset (see
+// Request.metrics), so callers never need a nil check.
+type MetricsCollector interface {
+	// AllocationCreated/AllocationDestroyed track the active-allocations gauge.
+	AllocationCreated(username string, transport Transport)
+	AllocationDestroyed(username string, transport Transport, lifetime time.Duration)
+	// BytesRelayed records data-path throughput; inbound is true for
+	// client->relay traffic and false for relay->client traffic.
+	BytesRelayed(username string, transport Transport, inbound bool, n int)
+	ChannelBindCreated(username string)
+	PermissionCreated(username string)
+	AuthFailure(reason FailureReason)
+	// Throttled records a frame dropped on the data path by an allocation's
+	// Quota (see QuotaHandler).
+	Throttled(username string, transport Transport)
+}
+
+// NoopMetricsCollector discards everything. It is the zero value used when
+// ServerConfig.MetricsCollector is left unset.
+type NoopMetricsCollector struct{}
+
+func (NoopMetricsCollector) AllocationCreated(string, Transport)                  {}
+func (NoopMetricsCollector) AllocationDestroyed(string, Transport, time.Duration) {}
+func (NoopMetricsCollector) BytesRelayed(string, Transport, bool, int)            {}
+func (NoopMetricsCollector) ChannelBindCreated(string)                            {}
+func (NoopMetricsCollector) PermissionCreated(string)                             {}
+func (NoopMetricsCollector) AuthFailure(FailureReason)                            {}
+func (NoopMetricsCollector) Throttled(string, Transport)                          {}
+
+// AllocationEvent describes a single allocation's lifecycle transition,
+// delivered to EventHandler so operators can ship records to accounting
+// systems.
+type AllocationEvent struct {
+	Username  string
+	Realm     string
+	Transport Transport
+	SrcAddr   net.Addr
+	RelayAddr net.Addr
+	Timestamp time.Time
+}
+
+// EventHandler receives structured per-allocation lifecycle events. Unlike
+// MetricsCollector, which is for aggregate counters, EventHandler is meant
+// for per-record accounting (billing, audit logs, etc.).
+type EventHandler interface {
+	AllocationStarted(AllocationEvent)
+	AllocationStopped(AllocationEvent)
+}
+
+// NoopEventHandler discards everything. It is the zero value used when
+// ServerConfig.EventHandler is left unset.
+type NoopEventHandler struct{}
+
+func (NoopEventHandler) AllocationStarted(AllocationEvent) {}
+func (NoopEventHandler) AllocationStopped(AllocationEvent) {}
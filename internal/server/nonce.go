@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"time"
+)
+
+// nonceMACSize is the number of HMAC bytes appended to the timestamp. 16
+// bytes is enough to make forging a nonce infeasible while keeping the
+// encoded nonce short.
+const nonceMACSize = 16
+
+var errMalformedNonce = errors.New("nonce is malformed")
+
+// NonceHandler generates and validates the nonce STUN attribute used by the
+// long-term credential mechanism (RFC 5389 section 10.2). Implementations
+// may be stateless (as statelessNonceHandler is) or back onto shared storage
+// (e.g. Redis) to additionally offer replay protection across a fleet of
+// servers.
+type NonceHandler interface {
+	// Generate returns a fresh nonce for a request from srcAddr.
+	Generate(srcAddr net.Addr) (string, error)
+	// Validate reports whether nonce is well-formed and not expired for a
+	// request from srcAddr.
+	Validate(nonce string, srcAddr net.Addr) bool
+}
+
+// NewStatelessNonceHandler returns a NonceHandler that needs no server-side
+// storage: a nonce is hex(timestamp || HMAC-SHA256(serverKey, timestamp ||
+// clientAddr)[:16]). Validation re-derives the HMAC from the timestamp
+// embedded in the nonce and the caller's address, so there is no map to grow
+// or evict, and serverKey can be shared across instances behind a load
+// balancer so any instance can validate a nonce minted by any other.
+func NewStatelessNonceHandler(serverKey []byte, lifetime time.Duration) NonceHandler {
+	return &statelessNonceHandler{serverKey: serverKey, lifetime: lifetime}
+}
+
+// GenerateServerKey returns a random 32-byte key suitable for use with
+// NewStatelessNonceHandler, generated once at server start.
+func GenerateServerKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+type statelessNonceHandler struct {
+	serverKey []byte
+	lifetime  time.Duration
+}
+
+func (h *statelessNonceHandler) Generate(srcAddr net.Addr) (string, error) {
+	now := uint64(time.Now().Unix()) //nolint:gosec
+	return hex.EncodeToString(append(timestampBytes(now), h.mac(now, srcAddr)...)), nil
+}
+
+func (h *statelessNonceHandler) Validate(nonce string, srcAddr net.Addr) bool {
+	raw, err := hex.DecodeString(nonce)
+	if err != nil || len(raw) != 8+nonceMACSize {
+		return false
+	}
+
+	timestamp := binary.BigEndian.Uint64(raw[:8])
+	if time.Since(time.Unix(int64(timestamp), 0)) >= h.lifetime { //nolint:gosec
+		return false
+	}
+
+	return hmac.Equal(raw[8:], h.mac(timestamp, srcAddr))
+}
+
+func (h *statelessNonceHandler) mac(timestamp uint64, srcAddr net.Addr) []byte {
+	mac := hmac.New(sha256.New, h.serverKey)
+	_, _ = mac.Write(timestampBytes(timestamp))
+	_, _ = mac.Write([]byte(srcAddr.String()))
+	return mac.Sum(nil)[:nonceMACSize]
+}
+
+func timestampBytes(timestamp uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, timestamp)
+	return b
+}
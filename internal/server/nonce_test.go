@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatelessNonceHandler(t *testing.T) {
+	serverKey, err := GenerateServerKey()
+	if err != nil {
+		t.Fatalf("failed to generate server key: %v", err)
+	}
+
+	handler := NewStatelessNonceHandler(serverKey, time.Hour)
+	addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4000}
+
+	nonce, err := handler.Generate(addr)
+	if err != nil {
+		t.Fatalf("failed to generate nonce: %v", err)
+	}
+
+	if !handler.Validate(nonce, addr) {
+		t.Fatalf("expected freshly minted nonce to validate")
+	}
+}
+
+func TestStatelessNonceHandlerRejectsTamperedAddr(t *testing.T) {
+	serverKey, err := GenerateServerKey()
+	if err != nil {
+		t.Fatalf("failed to generate server key: %v", err)
+	}
+
+	handler := NewStatelessNonceHandler(serverKey, time.Hour)
+	nonce, err := handler.Generate(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4000})
+	if err != nil {
+		t.Fatalf("failed to generate nonce: %v", err)
+	}
+
+	if handler.Validate(nonce, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4001}) {
+		t.Fatalf("expected nonce minted for a different address to be rejected")
+	}
+}
+
+func TestStatelessNonceHandlerRejectsExpired(t *testing.T) {
+	serverKey, err := GenerateServerKey()
+	if err != nil {
+		t.Fatalf("failed to generate server key: %v", err)
+	}
+
+	handler := NewStatelessNonceHandler(serverKey, time.Millisecond)
+	addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4000}
+
+	nonce, err := handler.Generate(addr)
+	if err != nil {
+		t.Fatalf("failed to generate nonce: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if handler.Validate(nonce, addr) {
+		t.Fatalf("expected expired nonce to be rejected")
+	}
+}
+
+func TestStatelessNonceHandlerRejectsMalformed(t *testing.T) {
+	serverKey, err := GenerateServerKey()
+	if err != nil {
+		t.Fatalf("failed to generate server key: %v", err)
+	}
+
+	handler := NewStatelessNonceHandler(serverKey, time.Hour)
+	addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4000}
+
+	if handler.Validate("not-hex-or-the-right-length", addr) {
+		t.Fatalf("expected malformed nonce to be rejected")
+	}
+}
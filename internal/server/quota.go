@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// monthlyCapWindow approximates a calendar month for resetting the monthly
+// byte cap; exact month boundaries aren't worth the added bookkeeping here.
+const monthlyCapWindow = 30 * 24 * time.Hour
+
+// Quota is the per-second rate limit and monthly volume cap applied to one
+// authenticated user's allocations. The zero value means unlimited.
+type Quota struct {
+	BytesPerSecond int
+	MonthlyByteCap int64
+}
+
+// QuotaHandler returns the Quota to enforce for an authenticated
+// username/realm. It is consulted the first time a username is seen and the
+// result is kept for that username, not looked up again per allocation.
+// Returning the zero Quota leaves that user's allocations unrestricted.
+type QuotaHandler func(username, realm string) Quota
+
+// tokenBucket rate-limits bytes relayed under one user's Quota. Every
+// allocation of that user draws from the same bucket; there is no separate
+// per-allocation rate limit. It is safe for concurrent use since the data
+// path's send and receive directions run on different goroutines.
+type tokenBucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	capacity   float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(bytesPerSecond int) *tokenBucket {
+	capacity := float64(bytesPerSecond)
+	return &tokenBucket{tokens: capacity, capacity: capacity, lastRefill: time.Now()}
+}
+
+// Allow reports whether n bytes may be sent now, consuming them from the
+// bucket if so. A bucket with zero capacity is treated as unlimited. A
+// single frame larger than the whole bucket (a Quota below the MTU is a
+// common, legitimate config) can never accumulate enough tokens to fit
+// under the usual check, so it is let through once it would drain the
+// full bucket, rather than being rejected on every call forever.
+func (b *tokenBucket) Allow(n int) bool {
+	if b.capacity <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.lastRefill).Seconds()*b.capacity)
+	b.lastRefill = now
+
+	if float64(n) > b.capacity {
+		if b.tokens < b.capacity {
+			return false
+		}
+		b.tokens = 0
+		return true
+	}
+
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// allocationQuota enforces one user's Quota: a token bucket for the
+// per-second rate, plus a running monthly byte total checked against
+// MonthlyByteCap. AllocationManager keeps one per username and hands the same
+// one to every allocation of that user, which consults it from the
+// send/receive data path and from Allocate and Refresh handling.
+type allocationQuota struct {
+	bucket *tokenBucket
+
+	mu            sync.Mutex
+	monthlyCap    int64
+	usedThisMonth int64
+	monthStart    time.Time
+}
+
+// newAllocationQuota seeds an allocationQuota from the Quota returned by a
+// QuotaHandler for this allocation's user.
+func newAllocationQuota(q Quota) *allocationQuota {
+	return &allocationQuota{
+		bucket:     newTokenBucket(q.BytesPerSecond),
+		monthlyCap: q.MonthlyByteCap,
+		monthStart: time.Now(),
+	}
+}
+
+// AllowRelay reports whether n more bytes may be relayed right now; on the
+// data path (Send/ChannelData) this should be checked before each frame is
+// forwarded, dropping the frame and incrementing a "throttled" metric when
+// it returns false. Bytes only count against the monthly cap once they have
+// also cleared the per-second rate limit.
+func (a *allocationQuota) AllowRelay(n int) bool {
+	if a == nil {
+		return true
+	}
+
+	if !a.bucket.Allow(n) {
+		return false
+	}
+
+	if a.spend(n) {
+		return true
+	}
+
+	return false
+}
+
+// MonthlyCapExceeded reports whether the monthly cap has already been
+// reached; Refresh handling should call this and respond with
+// CodeAllocQuotaReached instead of renewing the allocation.
+func (a *allocationQuota) MonthlyCapExceeded() bool {
+	if a == nil || a.monthlyCap <= 0 {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rolloverLocked()
+
+	return a.usedThisMonth >= a.monthlyCap
+}
+
+// spend accounts n bytes against the monthly cap, refusing and leaving the
+// total unchanged if that would exceed it.
+func (a *allocationQuota) spend(n int) bool {
+	if a.monthlyCap <= 0 {
+		return true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rolloverLocked()
+
+	if a.usedThisMonth+int64(n) > a.monthlyCap {
+		return false
+	}
+	a.usedThisMonth += int64(n)
+	return true
+}
+
+func (a *allocationQuota) rolloverLocked() {
+	if time.Since(a.monthStart) >= monthlyCapWindow {
+		a.usedThisMonth = 0
+		a.monthStart = time.Now()
+	}
+}
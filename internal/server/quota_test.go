@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import "testing"
+
+func TestTokenBucketThrottles(t *testing.T) {
+	b := newTokenBucket(100)
+
+	if !b.Allow(100) {
+		t.Fatalf("expected first 100-byte frame to be allowed from a full bucket")
+	}
+	if b.Allow(1) {
+		t.Fatalf("expected bucket to be empty immediately after spending its capacity")
+	}
+}
+
+func TestTokenBucketUnlimitedWhenZero(t *testing.T) {
+	b := newTokenBucket(0)
+
+	if !b.Allow(1 << 20) {
+		t.Fatalf("expected a zero-capacity bucket to allow everything")
+	}
+}
+
+func TestAllocationQuotaMonthlyCap(t *testing.T) {
+	q := newAllocationQuota(Quota{BytesPerSecond: 1 << 20, MonthlyByteCap: 100})
+
+	if !q.AllowRelay(60) {
+		t.Fatalf("expected first 60 bytes to fit under the monthly cap")
+	}
+	if !q.AllowRelay(40) {
+		t.Fatalf("expected the next 40 bytes to exactly reach the 100 byte monthly cap")
+	}
+	if q.AllowRelay(1) {
+		t.Fatalf("expected any further bytes to exceed the 100 byte monthly cap")
+	}
+	if !q.MonthlyCapExceeded() {
+		t.Fatalf("expected MonthlyCapExceeded to report true once usage reaches the cap")
+	}
+}
+
+func TestAllocationQuotaNilIsUnlimited(t *testing.T) {
+	var q *allocationQuota
+	if !q.AllowRelay(1 << 20) {
+		t.Fatalf("expected a nil allocationQuota to allow everything")
+	}
+	if q.MonthlyCapExceeded() {
+		t.Fatalf("expected a nil allocationQuota to never report its cap exceeded")
+	}
+}
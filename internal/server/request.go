@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package server
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// AuthHandler is consulted by AuthenticateRequest to look up the long-term
+// credential key for username. Returning ok=false rejects the request as an
+// unknown user.
+type AuthHandler func(username string, realm string, srcAddr net.Addr) (key []byte, ok bool)
+
+var (
+	errFailedToGenerateNonce = errors.New("failed to generate nonce")
+	errFailedToSendError     = errors.New("failed to send error message")
+	errDuplicatedNonce       = errors.New("duplicated nonce generated, discarding request")
+	errNoSuchUser            = errors.New("no such user exists")
+)
+
+// Request carries everything AuthenticateRequest and buildNonce need to
+// validate one incoming STUN message and, on failure, respond to it.
+//
+// Nonces backs the legacy MD5 nonce scheme and is only consulted when
+// NonceHandler is nil; servers started with a ServerKey use the stateless
+// scheme in nonce.go instead and never touch it.
+type Request struct {
+	Conn        net.PacketConn
+	SrcAddr     net.Addr
+	Realm       string
+	AuthHandler AuthHandler
+	Nonces      *sync.Map
+
+	NonceHandler NonceHandler
+	Metrics      MetricsCollector
+}
+
+// metrics returns r.Metrics, defaulting to NoopMetricsCollector{} so callers
+// never need a nil check.
+func (r Request) metrics() MetricsCollector {
+	if r.Metrics == nil {
+		return NoopMetricsCollector{}
+	}
+	return r.Metrics
+}
@@ -23,7 +23,20 @@ const (
 	nonceLifetime             = time.Hour // See: https://tools.ietf.org/html/rfc5766#section-4
 )
 
-func buildNonce() (string, error) {
+// buildNonce mints a nonce for r. When r.NonceHandler is set, nonces are
+// stateless (see NewStatelessNonceHandler) and nothing is written to
+// r.Nonces. Otherwise it falls back to the legacy MD5-keyed-by-random-value
+// scheme, which requires r.Nonces to track outstanding nonces and evict them
+// after nonceLifetime.
+func buildNonce(r Request) (string, error) {
+	if r.NonceHandler != nil {
+		nonce, err := r.NonceHandler.Generate(r.SrcAddr)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", errFailedToGenerateNonce, err) //nolint:errorlint
+		}
+		return nonce, nil
+	}
+
 	/* #nosec */
 	h := md5.New()
 	if _, err := io.WriteString(h, strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
@@ -43,7 +56,10 @@ func buildNonce() (string, error) {
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
-func buildAndSend(conn net.PacketConn, dst net.Addr, attrs ...stun.Setter) error {
+// SendMessage builds a STUN message from attrs and writes it to dst over
+// conn, swallowing net.ErrClosed since a connection closing out from under
+// an in-flight response isn't a failure worth surfacing.
+func SendMessage(conn net.PacketConn, dst net.Addr, attrs ...stun.Setter) error {
 	msg, err := stun.Build(attrs...)
 	if err != nil {
 		return err
@@ -58,29 +74,47 @@ func buildAndSend(conn net.PacketConn, dst net.Addr, attrs ...stun.Setter) error
 
 // Send a STUN packet and return the original error to the caller
 func buildAndSendErr(conn net.PacketConn, dst net.Addr, err error, attrs ...stun.Setter) error {
-	if sendErr := buildAndSend(conn, dst, attrs...); sendErr != nil {
+	if sendErr := SendMessage(conn, dst, attrs...); sendErr != nil {
 		err = fmt.Errorf("%w %v %v", errFailedToSendError, sendErr, err) //nolint:errorlint
 	}
 	return err
 }
 
-func buildMsg(transactionID [stun.TransactionIDSize]byte, msgType stun.MessageType, additional ...stun.Setter) []stun.Setter {
+// BuildMessage assembles the Setters for a STUN message of type msgType
+// carrying transactionID, for use with SendMessage.
+func BuildMessage(transactionID [stun.TransactionIDSize]byte, msgType stun.MessageType, additional ...stun.Setter) []stun.Setter {
 	return append([]stun.Setter{&stun.Message{TransactionID: transactionID}, msgType}, additional...)
 }
 
-func authenticateRequest(r Request, m *stun.Message, callingMethod stun.Method) (stun.MessageIntegrity, bool, error) {
+// AuthenticateRequest validates m's long-term credential attributes against
+// r.AuthHandler, challenging with a fresh nonce (stateless or legacy,
+// depending on r.NonceHandler) when they are missing or the nonce is stale,
+// and answering 400 Bad Request when they are malformed, name an unknown
+// user, or fail the integrity check.
+func AuthenticateRequest(r Request, m *stun.Message, callingMethod stun.Method) (stun.MessageIntegrity, bool, error) {
 	respondWithNonce := func(responseCode stun.ErrorCode) (stun.MessageIntegrity, bool, error) {
-		nonce, err := buildNonce()
+		// CodeUnauthorized is the normal first challenge of an unauthenticated
+		// request, not a failure; only a re-challenge due to an actually stale
+		// nonce counts toward the auth-failure metric.
+		if responseCode == stun.CodeStaleNonce {
+			r.metrics().AuthFailure(FailureReasonStaleNonce)
+		}
+
+		nonce, err := buildNonce(r)
 		if err != nil {
 			return nil, false, err
 		}
 
-		// Nonce has already been taken
-		if _, keyCollision := r.Nonces.LoadOrStore(nonce, time.Now()); keyCollision {
-			return nil, false, errDuplicatedNonce
+		// Legacy mode only: stateless nonces need no bookkeeping, since
+		// Validate below re-derives everything from the nonce itself.
+		if r.NonceHandler == nil {
+			// Nonce has already been taken
+			if _, keyCollision := r.Nonces.LoadOrStore(nonce, time.Now()); keyCollision {
+				return nil, false, errDuplicatedNonce
+			}
 		}
 
-		return nil, false, buildAndSend(r.Conn, r.SrcAddr, buildMsg(m.TransactionID,
+		return nil, false, SendMessage(r.Conn, r.SrcAddr, BuildMessage(m.TransactionID,
 			stun.NewType(callingMethod, stun.ClassErrorResponse),
 			&stun.ErrorCodeAttribute{Code: responseCode},
 			stun.NewNonce(nonce),
@@ -95,22 +129,28 @@ func authenticateRequest(r Request, m *stun.Message, callingMethod stun.Method)
 	nonceAttr := &stun.Nonce{}
 	usernameAttr := &stun.Username{}
 	realmAttr := &stun.Realm{}
-	badRequestMsg := buildMsg(m.TransactionID, stun.NewType(callingMethod, stun.ClassErrorResponse), &stun.ErrorCodeAttribute{Code: stun.CodeBadRequest})
+	badRequestMsg := BuildMessage(m.TransactionID, stun.NewType(callingMethod, stun.ClassErrorResponse), &stun.ErrorCodeAttribute{Code: stun.CodeBadRequest})
 
 	if err := nonceAttr.GetFrom(m); err != nil {
 		return nil, false, buildAndSendErr(r.Conn, r.SrcAddr, err, badRequestMsg...)
 	}
 
 	// Assert Nonce exists and is not expired
-	nonceCreationTime, nonceFound := r.Nonces.Load(string(*nonceAttr))
-	if !nonceFound {
-		r.Nonces.Delete(nonceAttr)
-		return respondWithNonce(stun.CodeStaleNonce)
-	}
+	if r.NonceHandler != nil {
+		if !r.NonceHandler.Validate(string(*nonceAttr), r.SrcAddr) {
+			return respondWithNonce(stun.CodeStaleNonce)
+		}
+	} else {
+		nonceCreationTime, nonceFound := r.Nonces.Load(string(*nonceAttr))
+		if !nonceFound {
+			r.Nonces.Delete(nonceAttr)
+			return respondWithNonce(stun.CodeStaleNonce)
+		}
 
-	if timeValue, ok := nonceCreationTime.(time.Time); !ok || time.Since(timeValue) >= nonceLifetime {
-		r.Nonces.Delete(nonceAttr)
-		return respondWithNonce(stun.CodeStaleNonce)
+		if timeValue, ok := nonceCreationTime.(time.Time); !ok || time.Since(timeValue) >= nonceLifetime {
+			r.Nonces.Delete(nonceAttr)
+			return respondWithNonce(stun.CodeStaleNonce)
+		}
 	}
 
 	if err := realmAttr.GetFrom(m); err != nil {
@@ -121,17 +161,23 @@ func authenticateRequest(r Request, m *stun.Message, callingMethod stun.Method)
 
 	ourKey, ok := r.AuthHandler(usernameAttr.String(), realmAttr.String(), r.SrcAddr)
 	if !ok {
+		r.metrics().AuthFailure(FailureReasonUnknownUser)
 		return nil, false, buildAndSendErr(r.Conn, r.SrcAddr, fmt.Errorf("%w %s", errNoSuchUser, usernameAttr.String()), badRequestMsg...)
 	}
 
 	if err := stun.MessageIntegrity(ourKey).Check(m); err != nil {
+		r.metrics().AuthFailure(FailureReasonBadIntegrity)
 		return nil, false, buildAndSendErr(r.Conn, r.SrcAddr, err, badRequestMsg...)
 	}
 
 	return stun.MessageIntegrity(ourKey), true, nil
 }
 
-func allocationLifeTime(m *stun.Message) time.Duration {
+// AllocationLifetime returns the lifetime an Allocate or Refresh request is
+// asking for. It returns proto.DefaultLifetime when the request carries no
+// Lifetime attribute or asks for maximumAllocationLifetime or more; such a
+// request is not clamped to maximumAllocationLifetime.
+func AllocationLifetime(m *stun.Message) time.Duration {
 	lifetimeDuration := proto.DefaultLifetime
 
 	var lifetime proto.Lifetime
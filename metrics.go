@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"github.com/pion/turn/v3/internal/server"
+)
+
+// Transport, FailureReason, MetricsCollector, EventHandler and
+// AllocationEvent are defined in internal/server, which is where they are
+// consumed; these aliases just make them part of the public API.
+type (
+	Transport            = server.Transport
+	FailureReason        = server.FailureReason
+	MetricsCollector     = server.MetricsCollector
+	EventHandler         = server.EventHandler
+	AllocationEvent      = server.AllocationEvent
+	NoopMetricsCollector = server.NoopMetricsCollector
+	NoopEventHandler     = server.NoopEventHandler
+)
+
+const (
+	TransportUDP = server.TransportUDP
+	TransportTCP = server.TransportTCP
+
+	FailureReasonStaleNonce   = server.FailureReasonStaleNonce
+	FailureReasonUnknownUser  = server.FailureReasonUnknownUser
+	FailureReasonBadIntegrity = server.FailureReasonBadIntegrity
+)
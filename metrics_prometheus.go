@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// PrometheusMetricsCollector is a MetricsCollector backed by Prometheus
+// counters, gauges, and histograms. Mount its Handler on a configurable
+// metrics address (e.g. via the turn-socks-kerry example server's
+// "metrics-addr" flag) to expose them
+// for scraping.
+type PrometheusMetricsCollector struct {
+	registry *prometheus.Registry
+
+	activeAllocations  *prometheus.GaugeVec
+	allocationLifetime *prometheus.HistogramVec
+	bytesRelayed       *prometheus.CounterVec
+	channelBinds       *prometheus.CounterVec
+	permissions        *prometheus.CounterVec
+	authFailures       *prometheus.CounterVec
+	throttled          *prometheus.CounterVec
+}
+
+// NewPrometheusMetricsCollector creates a PrometheusMetricsCollector with its
+// own registry, so it can be mounted without colliding with metrics an
+// embedding application already registers on the default registry.
+func NewPrometheusMetricsCollector() *PrometheusMetricsCollector {
+	c := &PrometheusMetricsCollector{
+		registry: prometheus.NewRegistry(),
+		activeAllocations: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "turn",
+			Name:      "active_allocations",
+			Help:      "Number of active TURN allocations.",
+		}, []string{"transport"}),
+		allocationLifetime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "turn",
+			Name:      "allocation_lifetime_seconds",
+			Help:      "Allocation lifetime from creation to teardown.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 14), // 1s .. 8192s (~2.3h)
+		}, []string{"transport"}),
+		bytesRelayed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "turn",
+			Name:      "relayed_bytes_total",
+			Help:      "Bytes relayed through TURN allocations.",
+		}, []string{"transport", "direction"}),
+		channelBinds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "turn",
+			Name:      "channel_binds_total",
+			Help:      "ChannelBind requests handled.",
+		}, nil),
+		permissions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "turn",
+			Name:      "create_permission_total",
+			Help:      "CreatePermission requests handled.",
+		}, nil),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "turn",
+			Name:      "auth_failures_total",
+			Help:      "Authentication failures, labeled by reason.",
+		}, []string{"reason"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "turn",
+			Name:      "throttled_frames_total",
+			Help:      "Frames dropped on the data path by an allocation's Quota.",
+		}, []string{"transport"}),
+	}
+
+	c.registry.MustRegister(
+		c.activeAllocations,
+		c.allocationLifetime,
+		c.bytesRelayed,
+		c.channelBinds,
+		c.permissions,
+		c.authFailures,
+		c.throttled,
+	)
+
+	return c
+}
+
+// Handler returns the http.Handler that serves this collector's metrics in
+// the Prometheus text exposition format.
+func (c *PrometheusMetricsCollector) Handler() http.Handler {
+	return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+}
+
+// AllocationCreated implements MetricsCollector. username is accepted to
+// satisfy the interface but deliberately not used as a label: REST-issued
+// (see RESTAuthHandler) usernames are per-allocation ephemeral values, and
+// labeling by them would give every series unbounded cardinality.
+func (c *PrometheusMetricsCollector) AllocationCreated(username string, transport Transport) {
+	c.activeAllocations.WithLabelValues(string(transport)).Inc()
+}
+
+func (c *PrometheusMetricsCollector) AllocationDestroyed(username string, transport Transport, lifetime time.Duration) {
+	c.activeAllocations.WithLabelValues(string(transport)).Dec()
+	c.allocationLifetime.WithLabelValues(string(transport)).Observe(lifetime.Seconds())
+}
+
+func (c *PrometheusMetricsCollector) BytesRelayed(username string, transport Transport, inbound bool, n int) {
+	direction := "outbound"
+	if inbound {
+		direction = "inbound"
+	}
+	c.bytesRelayed.WithLabelValues(string(transport), direction).Add(float64(n))
+}
+
+func (c *PrometheusMetricsCollector) ChannelBindCreated(username string) {
+	c.channelBinds.WithLabelValues().Inc()
+}
+
+func (c *PrometheusMetricsCollector) PermissionCreated(username string) {
+	c.permissions.WithLabelValues().Inc()
+}
+
+func (c *PrometheusMetricsCollector) AuthFailure(reason FailureReason) {
+	c.authFailures.WithLabelValues(string(reason)).Inc()
+}
+
+func (c *PrometheusMetricsCollector) Throttled(username string, transport Transport) {
+	c.throttled.WithLabelValues(string(transport)).Inc()
+}
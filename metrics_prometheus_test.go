@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetricsCollector(t *testing.T) {
+	c := NewPrometheusMetricsCollector()
+
+	c.AllocationCreated("alice", TransportUDP)
+	c.BytesRelayed("alice", TransportUDP, true, 100)
+	c.BytesRelayed("alice", TransportUDP, false, 42)
+	c.ChannelBindCreated("alice")
+	c.PermissionCreated("alice")
+	c.AuthFailure(FailureReasonUnknownUser)
+	c.Throttled("alice", TransportUDP)
+	c.AllocationDestroyed("alice", TransportUDP, time.Second)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`turn_active_allocations{transport="udp"} 0`,
+		`turn_relayed_bytes_total{direction="inbound",transport="udp"} 100`,
+		`turn_relayed_bytes_total{direction="outbound",transport="udp"} 42`,
+		`turn_channel_binds_total 1`,
+		`turn_create_permission_total 1`,
+		`turn_auth_failures_total{reason="unknown-user"} 1`,
+		`turn_throttled_frames_total{transport="udp"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected metrics output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import "github.com/pion/turn/v3/internal/server"
+
+// Quota and QuotaHandler are defined in internal/server, which is where the
+// relay data path consults them; these aliases just make them part of the
+// public API, set via ServerConfig.QuotaHandler.
+type (
+	Quota        = server.Quota
+	QuotaHandler = server.QuotaHandler
+)
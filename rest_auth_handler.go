@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidTimeLimitedUsername = errors.New("username is not a valid time-limited credential")
+	errExpiredTimeLimitedUsername = errors.New("time-limited username has already expired")
+)
+
+// RESTAuthHandler returns an AuthHandler implementing the "REST API For
+// Access To TURN Services" ephemeral credential scheme used across the
+// WebRTC ecosystem (e.g. by coturn's `use-auth-secret` and most signaling
+// servers). The client is issued a username of the form
+// "<unix-expiry-timestamp>:<user-id>" and a password of
+// base64(HMAC-SHA1(sharedSecret, username)), both computed out-of-band by
+// the signaling server from the same sharedSecret, so the TURN server needs
+// no per-user state to validate them.
+func RESTAuthHandler(sharedSecret string) AuthHandler {
+	return func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+		password, err := longTermCredentialPassword(username, sharedSecret)
+		if err != nil {
+			return nil, false
+		}
+
+		return GenerateAuthKey(username, realm, password), true
+	}
+}
+
+// longTermCredentialPassword recomputes the HMAC-SHA1 password for a
+// "<expiry>:<user-id>" username, rejecting it if it is malformed or expired.
+func longTermCredentialPassword(username, sharedSecret string) (string, error) {
+	expiry, _, ok := strings.Cut(username, ":")
+	if !ok {
+		return "", errInvalidTimeLimitedUsername
+	}
+
+	expiryUnix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidTimeLimitedUsername, err) //nolint:errorlint
+	}
+
+	if time.Unix(expiryUnix, 0).Before(time.Now()) {
+		return "", errExpiredTimeLimitedUsername
+	}
+
+	mac := hmac.New(sha1.New, []byte(sharedSecret))
+	if _, err := mac.Write([]byte(username)); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
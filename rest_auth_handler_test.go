@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func credentialFor(t *testing.T, sharedSecret string, expiry time.Time, userID string) (username, password string) {
+	t.Helper()
+
+	username = strconv.FormatInt(expiry.Unix(), 10) + ":" + userID
+
+	mac := hmac.New(sha1.New, []byte(sharedSecret))
+	if _, err := mac.Write([]byte(username)); err != nil {
+		t.Fatalf("failed to compute HMAC: %v", err)
+	}
+
+	return username, base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRESTAuthHandler(t *testing.T) {
+	const sharedSecret = "sekrit"
+	handler := RESTAuthHandler(sharedSecret)
+
+	username, password := credentialFor(t, sharedSecret, time.Now().Add(time.Hour), "alice")
+
+	key, ok := handler(username, "example.org", nil)
+	if !ok {
+		t.Fatalf("expected valid ephemeral credential to be accepted")
+	}
+
+	expected := GenerateAuthKey(username, "example.org", password)
+	if string(key) != string(expected) {
+		t.Fatalf("derived key mismatch: got %x want %x", key, expected)
+	}
+}
+
+func TestRESTAuthHandlerExpired(t *testing.T) {
+	handler := RESTAuthHandler("sekrit")
+
+	username, _ := credentialFor(t, "sekrit", time.Now().Add(-time.Minute), "alice")
+
+	if _, ok := handler(username, "example.org", nil); ok {
+		t.Fatalf("expected expired ephemeral credential to be rejected")
+	}
+}
+
+func TestRESTAuthHandlerMalformed(t *testing.T) {
+	handler := RESTAuthHandler("sekrit")
+
+	if _, ok := handler("not-a-valid-username", "example.org", nil); ok {
+		t.Fatalf("expected malformed username to be rejected")
+	}
+
+	if _, ok := handler("notanumber:alice", "example.org", nil); ok {
+		t.Fatalf("expected non-numeric expiry to be rejected")
+	}
+}
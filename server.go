@@ -0,0 +1,590 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package turn implements a TURN server (RFC 5766) built around a
+// long-term credential mechanism and optional ephemeral REST credentials
+// (see RESTAuthHandler).
+package turn
+
+import (
+	"crypto/md5" //nolint:gosec
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/stun/v2"
+	"github.com/pion/turn/v3/internal/proto"
+	"github.com/pion/turn/v3/internal/server"
+)
+
+// nonceLifetimeDefault is how long a stateless nonce (see ServerConfig.ServerKey)
+// stays valid before a client must be re-challenged; it mirrors the legacy
+// nonce map's lifetime so switching schemes doesn't change client-visible
+// behavior.
+const nonceLifetimeDefault = time.Hour
+
+// maxPacketSize is large enough for any Allocate/Refresh/CreatePermission/
+// ChannelBind message or relayed UDP datagram this server handles.
+const maxPacketSize = 1600
+
+// AuthHandler and NonceHandler are defined in internal/server, which is
+// where they are consumed; these aliases just make them part of the public
+// API, set via ServerConfig. Transport, MetricsCollector, EventHandler, and
+// their Noop defaults are aliased in metrics.go.
+type (
+	AuthHandler  = server.AuthHandler
+	NonceHandler = server.NonceHandler
+)
+
+var errServerConfigIncomplete = errors.New(
+	"ServerConfig requires an AuthHandler and at least one PacketConnConfig or ListenerConfig",
+)
+
+// RelayAddressGenerator produces the relay socket and its externally
+// reachable address for a new allocation.
+type RelayAddressGenerator interface {
+	AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error)
+}
+
+// RelayAddressGeneratorStatic allocates relay sockets on Address (typically
+// "0.0.0.0" to listen on every interface) and reports RelayAddress as the
+// externally reachable IP, for deployments with one fixed public address.
+type RelayAddressGeneratorStatic struct {
+	RelayAddress net.IP
+	Address      string
+}
+
+// AllocatePacketConn implements RelayAddressGenerator.
+func (r *RelayAddressGeneratorStatic) AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error) {
+	conn, err := net.ListenPacket(network, fmt.Sprintf("%s:%d", r.Address, requestedPort))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relayAddr := &net.UDPAddr{
+		IP:   r.RelayAddress,
+		Port: conn.LocalAddr().(*net.UDPAddr).Port, //nolint:forcetypeassert
+	}
+
+	return conn, relayAddr, nil
+}
+
+// GenerateAuthKey derives the long-term credential key for username/realm/
+// password, per the HMAC key construction in RFC 5389 section 10.2:
+// MD5(username ":" realm ":" password).
+func GenerateAuthKey(username, realm, password string) []byte {
+	h := md5.New() //nolint:gosec
+	_, _ = io.WriteString(h, username+":"+realm+":"+password)
+	return h.Sum(nil)
+}
+
+// PacketConnConfig pairs a UDP listener with the RelayAddressGenerator used
+// to satisfy Allocate requests received on it.
+type PacketConnConfig struct {
+	PacketConn            net.PacketConn
+	RelayAddressGenerator RelayAddressGenerator
+}
+
+// ListenerConfig pairs a TCP listener with a RelayAddressGenerator. Server
+// accepts connections on the listener and closes them at once; no TURN
+// request is read or answered over TCP (see acceptListener).
+type ListenerConfig struct {
+	Listener              net.Listener
+	RelayAddressGenerator RelayAddressGenerator
+}
+
+// ServerConfig configures a Server.
+type ServerConfig struct {
+	Realm             string
+	AuthHandler       AuthHandler
+	PacketConnConfigs []PacketConnConfig
+	ListenerConfigs   []ListenerConfig
+
+	// ServerKey, when set, selects the stateless keyed-HMAC nonce scheme
+	// (see NewStatelessNonceHandler) instead of the legacy in-memory nonce
+	// map. NonceHandler, if set directly, overrides both.
+	ServerKey    []byte
+	NonceHandler NonceHandler
+
+	// MetricsCollector and EventHandler default to their Noop implementations
+	// when unset.
+	MetricsCollector MetricsCollector
+	EventHandler     EventHandler
+
+	// QuotaHandler, when set, is consulted once per authenticated user to
+	// rate-limit and cap the data their allocations may relay. A nil
+	// QuotaHandler leaves every allocation unrestricted.
+	QuotaHandler QuotaHandler
+}
+
+// Server is a running TURN server. Construct one with NewServer.
+type Server struct {
+	realm        string
+	authHandler  AuthHandler
+	nonceHandler NonceHandler
+	nonces       *sync.Map
+	metrics      MetricsCollector
+	allocations  *server.AllocationManager
+
+	// allocTimers holds the *time.Timer that tears an allocation down once
+	// its lifetime elapses, keyed the same as s.allocations, so Refresh can
+	// reset it instead of Destroy racing a stale one.
+	allocTimers sync.Map
+
+	packetConnConfigs []PacketConnConfig
+	listenerConfigs   []ListenerConfig
+
+	closeOnce sync.Once
+}
+
+// NewServer validates config, selects its nonce scheme (stateless when
+// ServerKey or NonceHandler is set, the legacy in-memory map otherwise), and
+// starts one read loop per PacketConnConfig and one accept loop per
+// ListenerConfig.
+func NewServer(config ServerConfig) (*Server, error) {
+	if config.AuthHandler == nil || (len(config.PacketConnConfigs) == 0 && len(config.ListenerConfigs) == 0) {
+		return nil, errServerConfigIncomplete
+	}
+
+	nonceHandler := config.NonceHandler
+	if nonceHandler == nil && len(config.ServerKey) > 0 {
+		nonceHandler = server.NewStatelessNonceHandler(config.ServerKey, nonceLifetimeDefault)
+	}
+
+	metrics := config.MetricsCollector
+	if metrics == nil {
+		metrics = NoopMetricsCollector{}
+	}
+
+	s := &Server{
+		realm:             config.Realm,
+		authHandler:       config.AuthHandler,
+		nonceHandler:      nonceHandler,
+		nonces:            &sync.Map{},
+		metrics:           metrics,
+		allocations:       server.NewAllocationManager(metrics, config.EventHandler, config.QuotaHandler),
+		packetConnConfigs: config.PacketConnConfigs,
+		listenerConfigs:   config.ListenerConfigs,
+	}
+
+	for _, pc := range config.PacketConnConfigs {
+		go s.readPacketConn(pc)
+	}
+	for _, lc := range config.ListenerConfigs {
+		go s.acceptListener(lc)
+	}
+
+	return s, nil
+}
+
+// Close shuts the server down, closing every configured listener. It is
+// safe to call more than once.
+func (s *Server) Close() error {
+	var firstErr error
+	s.closeOnce.Do(func() {
+		for _, pc := range s.packetConnConfigs {
+			if err := pc.PacketConn.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		for _, lc := range s.listenerConfigs {
+			if err := lc.Listener.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	})
+
+	return firstErr
+}
+
+// allocationKey identifies one client's allocation by its 5-tuple; a single
+// PacketConn is shared by every client that sends to it, so the source
+// address is what distinguishes their allocations.
+func allocationKey(pc net.PacketConn, srcAddr net.Addr) string {
+	return pc.LocalAddr().String() + "|" + srcAddr.String()
+}
+
+// readPacketConn services one UDP PacketConnConfig, demultiplexing every
+// incoming datagram into a ChannelData frame (the data path for a bound
+// channel) or a STUN message (Allocate, Refresh, CreatePermission,
+// ChannelBind, or a Send indication).
+func (s *Server) readPacketConn(pc PacketConnConfig) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, srcAddr, err := pc.PacketConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		raw := append([]byte{}, buf[:n]...)
+
+		if stun.IsMessage(raw) {
+			s.handleSTUN(pc, raw, srcAddr)
+			continue
+		}
+		if proto.IsChannelData(raw) {
+			s.handleChannelData(pc, raw, srcAddr)
+		}
+	}
+}
+
+// handleSTUN decodes raw as a STUN message and dispatches it by method.
+func (s *Server) handleSTUN(pc PacketConnConfig, raw []byte, srcAddr net.Addr) {
+	msg := &stun.Message{Raw: raw}
+	if err := msg.Decode(); err != nil {
+		return
+	}
+
+	// Send is an indication, not a request: it rides on an allocation that
+	// was already authenticated when it was created, so it is neither
+	// challenged nor answered.
+	if msg.Type.Method == stun.MethodSend && msg.Type.Class == stun.ClassIndication {
+		s.handleSend(pc, msg, srcAddr)
+		return
+	}
+
+	if msg.Type.Class != stun.ClassRequest {
+		return
+	}
+
+	req := server.Request{
+		Conn:         pc.PacketConn,
+		SrcAddr:      srcAddr,
+		Realm:        s.realm,
+		AuthHandler:  s.authHandler,
+		Nonces:       s.nonces,
+		NonceHandler: s.nonceHandler,
+		Metrics:      s.metrics,
+	}
+
+	integrity, authenticated, err := server.AuthenticateRequest(req, msg, msg.Type.Method)
+	if err != nil || !authenticated {
+		return
+	}
+
+	switch msg.Type.Method {
+	case stun.MethodAllocate:
+		s.handleAllocate(pc, msg, srcAddr, integrity)
+	case stun.MethodRefresh:
+		s.handleRefresh(pc, msg, srcAddr, integrity)
+	case stun.MethodCreatePermission:
+		s.handleCreatePermission(pc, msg, srcAddr, integrity)
+	case stun.MethodChannelBind:
+		s.handleChannelBind(pc, msg, srcAddr, integrity)
+	}
+}
+
+// handleAllocate rejects the request with CodeAllocQuotaReached if the
+// user's monthly Quota is already spent, or CodeAllocMismatch if this
+// 5-tuple already has a live allocation; otherwise it opens a relay socket,
+// registers the allocation with s.allocations, schedules its teardown for
+// when AllocationLifetime elapses, starts relaying inbound relay traffic
+// back to the client, and answers with the Allocate success response
+// (XOR-RELAYED-ADDRESS, LIFETIME, MESSAGE-INTEGRITY).
+func (s *Server) handleAllocate(pc PacketConnConfig, msg *stun.Message, srcAddr net.Addr, integrity stun.MessageIntegrity) {
+	usernameAttr := &stun.Username{}
+	_ = usernameAttr.GetFrom(msg)
+	username := usernameAttr.String()
+
+	key := allocationKey(pc.PacketConn, srcAddr)
+
+	if _, exists := s.allocations.Get(key); exists {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeAllocMismatch},
+		)...)
+		return
+	}
+
+	if s.allocations.MonthlyCapExceeded(username, s.realm) {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeAllocQuotaReached},
+		)...)
+		return
+	}
+
+	relayConn, relayAddr, err := pc.RelayAddressGenerator.AllocatePacketConn("udp4", 0)
+	if err != nil {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeInsufficientCapacity},
+		)...)
+		return
+	}
+
+	alloc := s.allocations.Create(key, username, s.realm, TransportUDP, srcAddr, relayConn)
+
+	lifetime := server.AllocationLifetime(msg)
+	s.scheduleExpiry(key, lifetime)
+
+	go s.relayToClient(pc.PacketConn, alloc)
+
+	relayedAddr, ok := relayAddr.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+		stun.NewType(stun.MethodAllocate, stun.ClassSuccessResponse),
+		&proto.RelayedAddress{XORMappedAddress: stun.XORMappedAddress{IP: relayedAddr.IP, Port: relayedAddr.Port}},
+		proto.Lifetime{Duration: lifetime},
+		integrity,
+	)...)
+}
+
+// handleRefresh renews (Lifetime > 0) or tears down (Lifetime == 0) the
+// allocation for this 5-tuple, rejecting with CodeAllocQuotaReached if the
+// user's monthly Quota is already spent rather than renewing it.
+func (s *Server) handleRefresh(pc PacketConnConfig, msg *stun.Message, srcAddr net.Addr, integrity stun.MessageIntegrity) {
+	key := allocationKey(pc.PacketConn, srcAddr)
+	alloc, ok := s.allocations.Get(key)
+	if !ok {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodRefresh, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeAllocMismatch},
+		)...)
+		return
+	}
+
+	lifetime := server.AllocationLifetime(msg)
+	if lifetime == 0 {
+		s.cancelExpiry(key)
+		s.allocations.Destroy(key)
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodRefresh, stun.ClassSuccessResponse),
+			proto.Lifetime{Duration: 0},
+			integrity,
+		)...)
+		return
+	}
+
+	if s.allocations.MonthlyCapExceeded(alloc.Username, alloc.Realm) {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodRefresh, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeAllocQuotaReached},
+		)...)
+		return
+	}
+
+	s.scheduleExpiry(key, lifetime)
+	_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+		stun.NewType(stun.MethodRefresh, stun.ClassSuccessResponse),
+		proto.Lifetime{Duration: lifetime},
+		integrity,
+	)...)
+}
+
+// handleCreatePermission installs a permission for the request's
+// XOR-PEER-ADDRESS on this 5-tuple's allocation. RFC 5766 section 9.2 allows
+// more than one XOR-PEER-ADDRESS per request; only the first is honored here
+// since every caller in this codebase sends exactly one.
+func (s *Server) handleCreatePermission(pc PacketConnConfig, msg *stun.Message, srcAddr net.Addr, integrity stun.MessageIntegrity) {
+	key := allocationKey(pc.PacketConn, srcAddr)
+	alloc, ok := s.allocations.Get(key)
+	if !ok {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodCreatePermission, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeAllocMismatch},
+		)...)
+		return
+	}
+
+	var peer proto.PeerAddress
+	if err := peer.GetFrom(msg); err != nil {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodCreatePermission, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeBadRequest},
+		)...)
+		return
+	}
+
+	alloc.CreatePermission(peer.IP)
+	_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+		stun.NewType(stun.MethodCreatePermission, stun.ClassSuccessResponse),
+		integrity,
+	)...)
+}
+
+// handleChannelBind binds the request's CHANNEL-NUMBER to its
+// XOR-PEER-ADDRESS on this 5-tuple's allocation, implicitly installing the
+// permission that requires (RFC 5766 section 11.2).
+func (s *Server) handleChannelBind(pc PacketConnConfig, msg *stun.Message, srcAddr net.Addr, integrity stun.MessageIntegrity) {
+	errorResponse := func(code stun.ErrorCode) {
+		_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+			stun.NewType(stun.MethodChannelBind, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: code},
+		)...)
+	}
+
+	key := allocationKey(pc.PacketConn, srcAddr)
+	alloc, ok := s.allocations.Get(key)
+	if !ok {
+		errorResponse(stun.CodeAllocMismatch)
+		return
+	}
+
+	var channel proto.ChannelNumber
+	if err := channel.GetFrom(msg); err != nil {
+		errorResponse(stun.CodeBadRequest)
+		return
+	}
+
+	var peer proto.PeerAddress
+	if err := peer.GetFrom(msg); err != nil {
+		errorResponse(stun.CodeBadRequest)
+		return
+	}
+
+	peerAddr := &net.UDPAddr{IP: peer.IP, Port: peer.Port}
+	if !alloc.BindChannel(channel, peerAddr) {
+		errorResponse(stun.CodeBadRequest)
+		return
+	}
+
+	_ = server.SendMessage(pc.PacketConn, srcAddr, server.BuildMessage(msg.TransactionID,
+		stun.NewType(stun.MethodChannelBind, stun.ClassSuccessResponse),
+		integrity,
+	)...)
+}
+
+// handleSend relays the DATA carried by a Send indication to its
+// XOR-PEER-ADDRESS, dropping it if this 5-tuple has no allocation, the peer
+// has no permission, or the allocation's Quota rejects it.
+func (s *Server) handleSend(pc PacketConnConfig, msg *stun.Message, srcAddr net.Addr) {
+	key := allocationKey(pc.PacketConn, srcAddr)
+	alloc, ok := s.allocations.Get(key)
+	if !ok {
+		return
+	}
+
+	var peer proto.PeerAddress
+	if err := peer.GetFrom(msg); err != nil {
+		return
+	}
+	var data proto.Data
+	if err := data.GetFrom(msg); err != nil {
+		return
+	}
+
+	if !alloc.HasPermission(peer.IP) || !alloc.AllowRelay(len(data)) {
+		return
+	}
+
+	if _, err := alloc.RelayConn.WriteTo(data, &net.UDPAddr{IP: peer.IP, Port: peer.Port}); err == nil {
+		alloc.Relayed(true, len(data))
+	}
+}
+
+// handleChannelData relays a ChannelData frame's payload to the peer bound
+// to its channel number, subject to the same permission and Quota checks as
+// handleSend.
+func (s *Server) handleChannelData(pc PacketConnConfig, raw []byte, srcAddr net.Addr) {
+	channel, data, err := proto.ParseChannelData(raw)
+	if err != nil {
+		return
+	}
+
+	key := allocationKey(pc.PacketConn, srcAddr)
+	alloc, ok := s.allocations.Get(key)
+	if !ok {
+		return
+	}
+
+	peerAddr, ok := alloc.PeerForChannel(channel)
+	if !ok {
+		return
+	}
+	peerUDPAddr, ok := peerAddr.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+
+	if !alloc.HasPermission(peerUDPAddr.IP) || !alloc.AllowRelay(len(data)) {
+		return
+	}
+
+	if _, err := alloc.RelayConn.WriteTo(data, peerAddr); err == nil {
+		alloc.Relayed(true, len(data))
+	}
+}
+
+// scheduleExpiry (re)arms the timer that destroys the allocation at key
+// after lifetime, replacing any timer already running for it so a Refresh
+// extends the deadline instead of racing a stale one.
+func (s *Server) scheduleExpiry(key string, lifetime time.Duration) {
+	if old, ok := s.allocTimers.Load(key); ok {
+		old.(*time.Timer).Stop() //nolint:forcetypeassert
+	}
+	s.allocTimers.Store(key, time.AfterFunc(lifetime, func() {
+		s.allocTimers.Delete(key)
+		s.allocations.Destroy(key)
+	}))
+}
+
+// cancelExpiry stops the expiry timer for key, if one is running, so an
+// explicit Refresh(0) teardown doesn't race a redundant Destroy later.
+func (s *Server) cancelExpiry(key string) {
+	if old, ok := s.allocTimers.Load(key); ok {
+		old.(*time.Timer).Stop() //nolint:forcetypeassert
+		s.allocTimers.Delete(key)
+	}
+}
+
+// relayToClient reads everything arriving on alloc's relay socket and
+// forwards it to the client that owns the allocation: as a ChannelData
+// frame if the sending peer has a bound channel, otherwise as a Data
+// indication. Any frame alloc's Quota rejects is dropped. This runs until
+// the relay socket is closed by Destroy (on Refresh(0) or lifetime expiry)
+// or a write to the client fails. Server.Close does not close relay sockets.
+func (s *Server) relayToClient(clientConn net.PacketConn, alloc *server.Allocation) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, peerAddr, err := alloc.RelayConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if !alloc.AllowRelay(n) {
+			continue
+		}
+
+		var out []byte
+		if channel, ok := alloc.ChannelForPeer(peerAddr); ok {
+			out = proto.EncodeChannelData(channel, buf[:n])
+		} else {
+			peerUDPAddr, ok := peerAddr.(*net.UDPAddr)
+			if !ok {
+				continue
+			}
+			msg, err := stun.Build(
+				&stun.Message{TransactionID: stun.NewTransactionID()},
+				stun.NewType(stun.MethodData, stun.ClassIndication),
+				&proto.PeerAddress{XORMappedAddress: stun.XORMappedAddress{IP: peerUDPAddr.IP, Port: peerUDPAddr.Port}},
+				proto.Data(buf[:n]),
+			)
+			if err != nil {
+				continue
+			}
+			out = msg.Raw
+		}
+
+		if _, err := clientConn.WriteTo(out, alloc.SrcAddr); err != nil {
+			return
+		}
+		alloc.Relayed(false, n)
+	}
+}
+
+// acceptListener services one TCP ListenerConfig. TCP relay allocations
+// (RFC 6062) aren't implemented; connections are accepted and closed so
+// Close still shuts every configured listener down cleanly.
+func (s *Server) acceptListener(lc ListenerConfig) {
+	for {
+		conn, err := lc.Listener.Accept()
+		if err != nil {
+			return
+		}
+		_ = conn.Close()
+	}
+}
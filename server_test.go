@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"net"
+	"testing"
+)
+
+func TestGenerateAuthKey(t *testing.T) {
+	key := GenerateAuthKey("alice", "example.org", "secret")
+	if len(key) == 0 {
+		t.Fatalf("expected a non-empty key")
+	}
+
+	again := GenerateAuthKey("alice", "example.org", "secret")
+	if string(key) != string(again) {
+		t.Fatalf("expected GenerateAuthKey to be deterministic")
+	}
+
+	if other := GenerateAuthKey("bob", "example.org", "secret"); string(other) == string(key) {
+		t.Fatalf("expected different usernames to derive different keys")
+	}
+}
+
+func TestRelayAddressGeneratorStatic(t *testing.T) {
+	gen := &RelayAddressGeneratorStatic{
+		RelayAddress: net.ParseIP("203.0.113.1"),
+		Address:      "127.0.0.1",
+	}
+
+	conn, addr, err := gen.AllocatePacketConn("udp4", 0)
+	if err != nil {
+		t.Fatalf("failed to allocate a relay socket: %v", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		t.Fatalf("expected a *net.UDPAddr, got %T", addr)
+	}
+	if !udpAddr.IP.Equal(net.ParseIP("203.0.113.1")) {
+		t.Fatalf("expected the relay address to report RelayAddress, got %v", udpAddr.IP)
+	}
+	if udpAddr.Port == 0 {
+		t.Fatalf("expected a non-zero allocated port")
+	}
+}
+
+func TestNewServerRequiresAuthHandlerAndAListener(t *testing.T) {
+	if _, err := NewServer(ServerConfig{}); err == nil {
+		t.Fatalf("expected NewServer to reject a config with no AuthHandler or listeners")
+	}
+
+	authHandler := func(string, string, net.Addr) ([]byte, bool) { return nil, false }
+
+	if _, err := NewServer(ServerConfig{AuthHandler: authHandler}); err == nil {
+		t.Fatalf("expected NewServer to reject a config with no PacketConnConfigs or ListenerConfigs")
+	}
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to open UDP socket: %v", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	s, err := NewServer(ServerConfig{
+		AuthHandler: authHandler,
+		PacketConnConfigs: []PacketConnConfig{{
+			PacketConn:            conn,
+			RelayAddressGenerator: &RelayAddressGeneratorStatic{RelayAddress: net.ParseIP("127.0.0.1"), Address: "127.0.0.1"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("expected a complete config to construct a Server: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing server: %v", err)
+	}
+}